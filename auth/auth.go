@@ -0,0 +1,232 @@
+// Package auth restricts the dashboard and API to known users and tokens.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// verifiedTTL is how long a successful basic auth login is remembered, so
+// that bcrypt doesn't run on every request.
+const verifiedTTL = 5 * time.Minute
+
+// Identity is who sent a request.
+type Identity struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// Can reports whether the identity has role. Admins have every role.
+func (i Identity) Can(role string) bool {
+	return i.Role == config.RoleAdmin || i.Role == role
+}
+
+type contextKey struct{}
+
+// FromContext returns the identity of an authenticated request.
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
+
+// Authenticator identifies requests from API tokens, basic auth and OIDC
+// sessions, and checks their role.
+type Authenticator struct {
+	cfg            config.Authentication
+	prefix         string
+	allowedOrigins []string
+	oidc           *provider
+	sessions       *sessionStore
+	now            func() time.Time
+
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]verifiedUser
+}
+
+type verifiedUser struct {
+	identity Identity
+	expires  time.Time
+}
+
+func New(cfg *config.Config) *Authenticator {
+	a := &Authenticator{
+		cfg:            cfg.Auth,
+		prefix:         cfg.Server.Prefix(),
+		allowedOrigins: cfg.Server.AllowedOrigins,
+		sessions:       newSessionStore(),
+		now:            time.Now,
+		verified:       make(map[[sha256.Size]byte]verifiedUser),
+	}
+	if cfg.Auth.OIDC != nil {
+		a.oidc = newProvider(cfg.Auth.OIDC)
+	}
+	return a
+}
+
+// Enabled reports whether requests must be authenticated.
+func (a *Authenticator) Enabled() bool {
+	return a.cfg.Enabled()
+}
+
+// Authenticate identifies the sender of r from an API token, basic auth
+// credentials or a session cookie.
+func (a *Authenticator) Authenticate(r *http.Request) (Identity, bool) {
+	if token, ok := bearerToken(r); ok {
+		return a.checkToken(token)
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		return a.checkUser(username, password)
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		return a.sessions.get(cookie.Value, a.now())
+	}
+	return Identity{}, false
+}
+
+// Require wraps next so that it only serves requests from identities with
+// role. Requests that change something must also come from an allowed
+// origin, so that other sites can't use the browser's credentials.
+func (a *Authenticator) Require(role string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next(w, r)
+			return
+		}
+
+		identity, ok := a.Authenticate(r)
+		if !ok {
+			a.challenge(w, r)
+			return
+		}
+		if !identity.Can(role) {
+			writeError(w, http.StatusForbidden, "insufficient role")
+			return
+		}
+		if !safeMethod(r.Method) && !a.CheckOrigin(r) {
+			writeError(w, http.StatusForbidden, "origin not allowed")
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, identity)))
+	}
+}
+
+// CheckOrigin reports whether a browser request comes from the server's own
+// origin or one of the allowed origins. Requests without an Origin header
+// don't come from a browser and are allowed.
+func (a *Authenticator) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range a.allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// challenge answers an unauthenticated request, sending browsers to the OIDC
+// login when there is one.
+func (a *Authenticator) challenge(w http.ResponseWriter, r *http.Request) {
+	if a.oidc != nil && r.Method == http.MethodGet && strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, a.prefix+"/auth/login?return="+url.QueryEscape(r.RequestURI), http.StatusFound)
+		return
+	}
+	if len(a.cfg.Users) > 0 {
+		w.Header().Set("WWW-Authenticate", `Basic realm="statusy", charset="UTF-8"`)
+	}
+	writeError(w, http.StatusUnauthorized, "authentication required")
+}
+
+func (a *Authenticator) checkToken(token string) (Identity, bool) {
+	sum := sha256.Sum256([]byte(token))
+	for _, t := range a.cfg.Tokens {
+		expected := sha256.Sum256([]byte(t.Token))
+		if subtle.ConstantTimeCompare(sum[:], expected[:]) == 1 {
+			return Identity{Name: t.Name, Role: t.Role}, true
+		}
+	}
+	return Identity{}, false
+}
+
+func (a *Authenticator) checkUser(username, password string) (Identity, bool) {
+	key := sha256.Sum256([]byte(username + "\x00" + password))
+	now := a.now()
+
+	a.mu.Lock()
+	verified, ok := a.verified[key]
+	a.mu.Unlock()
+	if ok && now.Before(verified.expires) {
+		return verified.identity, true
+	}
+
+	for _, user := range a.cfg.Users {
+		if user.Username != username {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+			return Identity{}, false
+		}
+
+		identity := Identity{Name: user.Username, Role: user.Role}
+		a.mu.Lock()
+		for k, v := range a.verified {
+			if !now.Before(v.expires) {
+				delete(a.verified, k)
+			}
+		}
+		a.verified[key] = verifiedUser{identity: identity, expires: now.Add(verifiedTTL)}
+		a.mu.Unlock()
+		return identity, true
+	}
+	return Identity{}, false
+}
+
+// bearerToken returns the API token of a request. Browsers can't set headers
+// on WebSocket and EventSource connections, so streams may pass the token as
+// the access_token query parameter instead.
+func bearerToken(r *http.Request) (string, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token, true
+	}
+	if isStream(r) {
+		if token := r.URL.Query().Get("access_token"); token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+func isStream(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newTestAuthenticator(t *testing.T) *Authenticator {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	return New(&config.Config{
+		Auth: config.Authentication{
+			Tokens: []config.APIToken{
+				{Name: "grafana", Token: "read-token", Role: config.RoleRead},
+				{Name: "ops", Token: "admin-token", Role: config.RoleAdmin},
+			},
+			Users: []config.User{
+				{Username: "jane", PasswordHash: string(hash), Role: config.RoleAdmin},
+			},
+		},
+		Server: config.Server{AllowedOrigins: []string{"https://dashboard.example.com/"}},
+	})
+}
+
+func TestRequire(t *testing.T) {
+	a := newTestAuthenticator(t)
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := FromContext(r.Context())
+		w.Write([]byte(identity.Name))
+	}
+
+	tests := []struct {
+		name     string
+		role     string
+		method   string
+		setup    func(r *http.Request)
+		status   int
+		identity string
+	}{
+		{
+			name:   "anonymous",
+			role:   config.RoleRead,
+			setup:  func(r *http.Request) {},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:     "read token",
+			role:     config.RoleRead,
+			setup:    func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-token") },
+			status:   http.StatusOK,
+			identity: "grafana",
+		},
+		{
+			name:   "read token on admin route",
+			role:   config.RoleAdmin,
+			method: http.MethodPost,
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer read-token") },
+			status: http.StatusForbidden,
+		},
+		{
+			name:     "admin token on admin route",
+			role:     config.RoleAdmin,
+			method:   http.MethodPost,
+			setup:    func(r *http.Request) { r.Header.Set("Authorization", "Bearer admin-token") },
+			status:   http.StatusOK,
+			identity: "ops",
+		},
+		{
+			name:   "unknown token",
+			role:   config.RoleRead,
+			setup:  func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "token in query outside of streams",
+			role:   config.RoleRead,
+			setup:  func(r *http.Request) { r.URL.RawQuery = "access_token=read-token" },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "token in query on a stream",
+			role: config.RoleRead,
+			setup: func(r *http.Request) {
+				r.URL.RawQuery = "access_token=read-token"
+				r.Header.Set("Accept", "text/event-stream")
+			},
+			status:   http.StatusOK,
+			identity: "grafana",
+		},
+		{
+			name:     "basic auth",
+			role:     config.RoleAdmin,
+			setup:    func(r *http.Request) { r.SetBasicAuth("jane", "secret") },
+			status:   http.StatusOK,
+			identity: "jane",
+		},
+		{
+			name:   "wrong password",
+			role:   config.RoleRead,
+			setup:  func(r *http.Request) { r.SetBasicAuth("jane", "wrong") },
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "cross-site change",
+			role:   config.RoleAdmin,
+			method: http.MethodPost,
+			setup: func(r *http.Request) {
+				r.SetBasicAuth("jane", "secret")
+				r.Header.Set("Origin", "https://evil.example.com")
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "change from allowed origin",
+			role:   config.RoleAdmin,
+			method: http.MethodPost,
+			setup: func(r *http.Request) {
+				r.SetBasicAuth("jane", "secret")
+				r.Header.Set("Origin", "https://dashboard.example.com")
+			},
+			status:   http.StatusOK,
+			identity: "jane",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			r := httptest.NewRequest(method, "http://statusy.example.com/api/v1/incidents", nil)
+			tt.setup(r)
+			w := httptest.NewRecorder()
+
+			a.Require(tt.role, ok)(w, r)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.identity, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequire_Challenge(t *testing.T) {
+	a := newTestAuthenticator(t)
+	w := httptest.NewRecorder()
+	a.Require(config.RoleRead, nil)(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+}
+
+func TestRequire_Disabled(t *testing.T) {
+	a := New(&config.Config{})
+	w := httptest.NewRecorder()
+	a.Require(config.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})(w, httptest.NewRequest(http.MethodPost, "/api/v1/incidents/1/ack", nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestCheckOrigin(t *testing.T) {
+	a := newTestAuthenticator(t)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true},
+		{"https://statusy.example.com", true},
+		{"https://dashboard.example.com", true},
+		{"https://evil.example.com", false},
+		{"://", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://statusy.example.com/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			assert.Equal(t, tt.allowed, a.CheckOrigin(r))
+		})
+	}
+}
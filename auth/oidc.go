@@ -0,0 +1,312 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+)
+
+const (
+	stateCookie = "statusy_oidc_state"
+	// stateTTL is how long a user may take to log in with the provider
+	stateTTL = 10 * time.Minute
+)
+
+var defaultScopes = []string{"openid", "email", "profile"}
+
+// provider logs users in with the OIDC authorization code flow. Users are
+// identified with the userinfo endpoint, so no ID token has to be verified.
+type provider struct {
+	cfg    *config.OIDC
+	client *http.Client
+
+	mu        sync.Mutex
+	endpoints *endpoints
+}
+
+type endpoints struct {
+	Auth     string `json:"authorization_endpoint"`
+	Token    string `json:"token_endpoint"`
+	Userinfo string `json:"userinfo_endpoint"`
+}
+
+type userinfo struct {
+	Subject           string       `json:"sub"`
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	PreferredUsername string       `json:"preferred_username"`
+}
+
+// flexibleBool accepts a JSON boolean as well as the strings "true" and
+// "false", which some providers send for email_verified instead.
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case bool:
+		*b = flexibleBool(value)
+	case string:
+		*b = value == "true"
+	case nil:
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+func newProvider(cfg *config.OIDC) *provider {
+	return &provider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// discover returns the provider's endpoints, discovering the ones that
+// aren't configured from the issuer on first use.
+func (p *provider) discover(ctx context.Context) (endpoints, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.endpoints != nil {
+		return *p.endpoints, nil
+	}
+
+	discovered := endpoints{Auth: p.cfg.AuthURL, Token: p.cfg.TokenURL, Userinfo: p.cfg.UserinfoURL}
+	if discovered.Auth == "" || discovered.Token == "" || discovered.Userinfo == "" {
+		var document endpoints
+		wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+		if err := p.getJSON(ctx, wellKnown, "", &document); err != nil {
+			return endpoints{}, fmt.Errorf("discovery failed: %w", err)
+		}
+		if discovered.Auth == "" {
+			discovered.Auth = document.Auth
+		}
+		if discovered.Token == "" {
+			discovered.Token = document.Token
+		}
+		if discovered.Userinfo == "" {
+			discovered.Userinfo = document.Userinfo
+		}
+	}
+
+	p.endpoints = &discovered
+	return discovered, nil
+}
+
+func (p *provider) getJSON(ctx context.Context, url, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// exchange trades an authorization code for an access token.
+func (p *provider) exchange(ctx context.Context, tokenURL, code, redirectURL string) (string, error) {
+	form := url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURL},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("token endpoint returned no access token")
+	}
+	return token.AccessToken, nil
+}
+
+// identify maps a user of the provider to an identity, if they may log in.
+func (p *provider) identify(info userinfo) (Identity, bool) {
+	name := info.Subject
+	// An unverified email could belong to anyone
+	if info.Email != "" && info.EmailVerified {
+		name = info.Email
+	}
+	if name == "" {
+		return Identity{}, false
+	}
+
+	switch {
+	case slices.Contains(p.cfg.Admins, name):
+		return Identity{Name: name, Role: config.RoleAdmin}, true
+	case slices.Contains(p.cfg.Readers, name):
+		return Identity{Name: name, Role: config.RoleRead}, true
+	}
+	return Identity{}, false
+}
+
+func (a *Authenticator) redirectURL(r *http.Request) string {
+	if a.oidc.cfg.RedirectURL != "" {
+		return a.oidc.cfg.RedirectURL
+	}
+	scheme := "http"
+	if isSecure(r) {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + a.prefix + "/auth/callback"
+}
+
+// returnPath only allows returning to a path on this server after logging in.
+func (a *Authenticator) returnPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return a.prefix + "/"
+	}
+	return path
+}
+
+// HandleLogin sends the user to the OIDC provider.
+func (a *Authenticator) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if a.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	endpoints, err := a.oidc.discover(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	state := randomString()
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookie,
+		Value:    state + "|" + a.returnPath(r.URL.Query().Get("return")),
+		Path:     a.prefix + "/auth/",
+		MaxAge:   int(stateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   isSecure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	scopes := a.oidc.cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {a.oidc.cfg.ClientID},
+		"redirect_uri":  {a.redirectURL(r)},
+		"scope":         {strings.Join(scopes, " ")},
+		"state":         {state},
+	}
+
+	separator := "?"
+	if strings.Contains(endpoints.Auth, "?") {
+		separator = "&"
+	}
+	http.Redirect(w, r, endpoints.Auth+separator+query.Encode(), http.StatusFound)
+}
+
+// HandleCallback completes a login started by HandleLogin.
+func (a *Authenticator) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	if a.oidc == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	cookie, err := r.Cookie(stateCookie)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "login expired, please try again")
+		return
+	}
+	state, returnTo, _ := strings.Cut(cookie.Value, "|")
+	if state == "" || r.URL.Query().Get("state") != state {
+		writeError(w, http.StatusBadRequest, "invalid login state")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookie, Path: a.prefix + "/auth/", MaxAge: -1})
+
+	if reason := r.URL.Query().Get("error"); reason != "" {
+		writeError(w, http.StatusUnauthorized, "login failed: "+reason)
+		return
+	}
+
+	endpoints, err := a.oidc.discover(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	accessToken, err := a.oidc.exchange(r.Context(), endpoints.Token, r.URL.Query().Get("code"), a.redirectURL(r))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	var info userinfo
+	if err := a.oidc.getJSON(r.Context(), endpoints.Userinfo, accessToken, &info); err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	identity, ok := a.oidc.identify(info)
+	if !ok {
+		writeError(w, http.StatusForbidden, "user not allowed")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    a.sessions.create(identity, a.now()),
+		Path:     a.prefix + "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   isSecure(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, a.returnPath(returnTo), http.StatusFound)
+}
+
+// HandleLogout ends the session of the user.
+func (a *Authenticator) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		a.sessions.delete(cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: a.prefix + "/", MaxAge: -1})
+	http.Redirect(w, r, a.prefix+"/", http.StatusFound)
+}
@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// newOIDCProvider starts a stand-in OIDC provider that logs in whoever the
+// email points to without asking.
+func newOIDCProvider(t *testing.T, email *string) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/authorize", func(w http.ResponseWriter, r *http.Request) {
+		redirect, _ := url.Parse(r.URL.Query().Get("redirect_uri"))
+		redirect.RawQuery = url.Values{"code": {"code-123"}, "state": {r.URL.Query().Get("state")}}.Encode()
+		http.Redirect(w, r, redirect.String(), http.StatusFound)
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, _ := r.BasicAuth()
+		if clientID != "statusy" || clientSecret != "secret" || r.FormValue("code") != "code-123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access-123", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access-123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"sub": "42", "email": *email, "email_verified": true})
+	})
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOIDCLogin(t *testing.T) {
+	email := "jane@example.com"
+	provider := newOIDCProvider(t, &email)
+
+	a := New(&config.Config{
+		Auth: config.Authentication{
+			OIDC: &config.OIDC{
+				Issuer:       provider.URL,
+				ClientID:     "statusy",
+				ClientSecret: "secret",
+				Admins:       []string{"jane@example.com"},
+				Readers:      []string{"john@example.com"},
+			},
+		},
+		Server: config.Server{BasePath: "/status"},
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /status/auth/login", a.HandleLogin)
+	mux.HandleFunc("GET /status/auth/callback", a.HandleCallback)
+	mux.HandleFunc("GET /status/auth/logout", a.HandleLogout)
+	mux.HandleFunc("/status/", a.Require(config.RoleAdmin, func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := FromContext(r.Context())
+		w.Write([]byte(identity.Name + " " + r.URL.Path))
+	}))
+	app := httptest.NewServer(mux)
+	defer app.Close()
+
+	jar, _ := cookiejar.New(nil)
+	browser := &http.Client{Jar: jar}
+	get := func(path string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodGet, app.URL+path, nil)
+		req.Header.Set("Accept", "text/html")
+		resp, err := browser.Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		var body [256]byte
+		n, _ := resp.Body.Read(body[:])
+		return resp, string(body[:n])
+	}
+
+	// Logging in returns to the page that was asked for
+	resp, body := get("/status/history")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "jane@example.com /status/history", body)
+
+	// The session is kept
+	resp, body = get("/status/")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "jane@example.com /status/", body)
+
+	// Readers can log in but not use admin routes. Logging out returns to the
+	// dashboard, which logs in again as whoever the provider says.
+	email = "john@example.com"
+	get("/status/auth/logout")
+	resp, _ = get("/status/incidents")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Unknown users can't log in
+	email = "mallory@example.com"
+	resp, _ = get("/status/auth/logout")
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestOIDCCallback_InvalidState(t *testing.T) {
+	a := New(&config.Config{
+		Auth: config.Authentication{
+			OIDC: &config.OIDC{AuthURL: "https://idp.example.com/authorize", TokenURL: "https://idp.example.com/token", UserinfoURL: "https://idp.example.com/userinfo", ClientID: "statusy"},
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/auth/callback?code=abc&state=forged", nil)
+	r.AddCookie(&http.Cookie{Name: stateCookie, Value: "expected|/"})
+	w := httptest.NewRecorder()
+	a.HandleCallback(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestReturnPath(t *testing.T) {
+	a := &Authenticator{prefix: "/status"}
+
+	assert.Equal(t, "/status/history", a.returnPath("/status/history"))
+	assert.Equal(t, "/status/", a.returnPath("https://evil.example.com"))
+	assert.Equal(t, "/status/", a.returnPath("//evil.example.com"))
+	assert.Equal(t, "/status/", a.returnPath(""))
+}
+
+func TestUserinfo_EmailVerified(t *testing.T) {
+	tests := []struct {
+		data     string
+		verified bool
+	}{
+		{data: `{"email_verified": true}`, verified: true},
+		{data: `{"email_verified": false}`, verified: false},
+		{data: `{"email_verified": "true"}`, verified: true},
+		{data: `{"email_verified": "false"}`, verified: false},
+		{data: `{"email_verified": null}`, verified: false},
+		{data: `{}`, verified: false},
+	}
+
+	for _, tt := range tests {
+		var info userinfo
+		assert.NoError(t, json.Unmarshal([]byte(tt.data), &info), tt.data)
+		assert.Equal(t, tt.verified, bool(info.EmailVerified), tt.data)
+	}
+
+	var info userinfo
+	assert.Error(t, json.Unmarshal([]byte(`{"email_verified": 1}`), &info))
+}
+
+func TestIdentify(t *testing.T) {
+	p := newProvider(&config.OIDC{
+		Admins:  []string{"jane@example.com", "42"},
+		Readers: []string{"john@example.com", "7"},
+	})
+
+	tests := []struct {
+		name     string
+		info     userinfo
+		identity Identity
+		ok       bool
+	}{
+		{
+			name:     "verified email",
+			info:     userinfo{Subject: "1", Email: "jane@example.com", EmailVerified: true},
+			identity: Identity{Name: "jane@example.com", Role: config.RoleAdmin},
+			ok:       true,
+		},
+		{
+			name:     "verified reader",
+			info:     userinfo{Subject: "1", Email: "john@example.com", EmailVerified: true},
+			identity: Identity{Name: "john@example.com", Role: config.RoleRead},
+			ok:       true,
+		},
+		{
+			name: "unverified email",
+			info: userinfo{Subject: "1", Email: "jane@example.com"},
+		},
+		{
+			name:     "unverified email falls back to the subject",
+			info:     userinfo{Subject: "42", Email: "jane@example.com"},
+			identity: Identity{Name: "42", Role: config.RoleAdmin},
+			ok:       true,
+		},
+		{
+			name:     "subject without email",
+			info:     userinfo{Subject: "7"},
+			identity: Identity{Name: "7", Role: config.RoleRead},
+			ok:       true,
+		},
+		{
+			name: "unknown user",
+			info: userinfo{Subject: "1", Email: "eve@example.com", EmailVerified: true},
+		},
+		{
+			name: "no identity",
+			info: userinfo{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, ok := p.identify(tt.info)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.identity, identity)
+		})
+	}
+
+	// Without readers, only admins can log in
+	p = newProvider(&config.OIDC{Admins: []string{"jane@example.com"}})
+	_, ok := p.identify(userinfo{Subject: "1", Email: "john@example.com", EmailVerified: true})
+	assert.False(t, ok)
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookie = "statusy_session"
+	// sessionTTL is how long an OIDC login lasts
+	sessionTTL = 12 * time.Hour
+)
+
+// sessionStore keeps the sessions of users logged in with OIDC. Sessions
+// live in memory, so users log in again after a restart.
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+type session struct {
+	identity Identity
+	expires  time.Time
+}
+
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]session)}
+}
+
+func (s *sessionStore) create(identity Identity, now time.Time) string {
+	id := randomString()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, session := range s.sessions {
+		if !now.Before(session.expires) {
+			delete(s.sessions, key)
+		}
+	}
+	s.sessions[id] = session{identity: identity, expires: now.Add(sessionTTL)}
+	return id
+}
+
+func (s *sessionStore) get(id string, now time.Time) (Identity, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || !now.Before(session.expires) {
+		return Identity{}, false
+	}
+	return session.identity, true
+}
+
+func (s *sessionStore) delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+func randomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// isSecure reports whether the client reached the server over HTTPS, either
+// directly or through a proxy.
+func isSecure(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Roles grant access to the dashboard and API. RoleAdmin includes RoleRead.
+const (
+	// RoleRead can view the dashboard and read the API
+	RoleRead = "read"
+	// RoleAdmin can also acknowledge incidents and post updates
+	RoleAdmin = "admin"
+)
+
+// Authentication restricts the dashboard and API. It is enabled as soon as any
+// token, user or OIDC provider is configured. The public status page and badges
+// stay public.
+type Authentication struct {
+	Tokens []APIToken `yaml:"tokens,omitempty"`
+	Users  []User     `yaml:"users,omitempty"`
+	OIDC   *OIDC      `yaml:"oidc,omitempty"`
+}
+
+// APIToken is a static token sent as "Authorization: Bearer <token>".
+type APIToken struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Role  string `yaml:"role"`
+}
+
+// User logs in with basic auth.
+type User struct {
+	Username string `yaml:"username"`
+	// PasswordHash is a bcrypt hash, e.g. from "htpasswd -nbBC 10 user password"
+	PasswordHash string `yaml:"password_hash"`
+	Role         string `yaml:"role"`
+}
+
+// OIDC logs users in with an OpenID Connect provider. Users are identified
+// by the email, or the subject when there is none, from the userinfo
+// endpoint.
+type OIDC struct {
+	// Issuer is used to discover the endpoints that aren't set explicitly
+	Issuer       string `yaml:"issuer,omitempty"`
+	AuthURL      string `yaml:"auth_url,omitempty"`
+	TokenURL     string `yaml:"token_url,omitempty"`
+	UserinfoURL  string `yaml:"userinfo_url,omitempty"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	// RedirectURL defaults to /auth/callback on the host of the request
+	RedirectURL string   `yaml:"redirect_url,omitempty"`
+	Scopes      []string `yaml:"scopes,omitempty"`
+	// Admins get RoleAdmin
+	Admins []string `yaml:"admins,omitempty"`
+	// Readers get RoleRead. Users of the provider who are neither admins nor
+	// readers can't log in.
+	Readers []string `yaml:"readers,omitempty"`
+}
+
+// Enabled reports whether authentication is required.
+func (a Authentication) Enabled() bool {
+	return len(a.Tokens) > 0 || len(a.Users) > 0 || a.OIDC != nil
+}
+
+func validRole(role string) error {
+	if role != RoleRead && role != RoleAdmin {
+		return fmt.Errorf("role must be %s or %s", RoleRead, RoleAdmin)
+	}
+	return nil
+}
+
+func (a Authentication) validate() error {
+	for _, token := range a.Tokens {
+		if token.Token == "" {
+			return fmt.Errorf("token %q requires a token", token.Name)
+		}
+		if err := validRole(token.Role); err != nil {
+			return fmt.Errorf("token %q: %w", token.Name, err)
+		}
+	}
+	for _, user := range a.Users {
+		if user.Username == "" {
+			return fmt.Errorf("user requires a username")
+		}
+		if _, err := bcrypt.Cost([]byte(user.PasswordHash)); err != nil {
+			return fmt.Errorf("user %q: password_hash must be a bcrypt hash", user.Username)
+		}
+		if err := validRole(user.Role); err != nil {
+			return fmt.Errorf("user %q: %w", user.Username, err)
+		}
+	}
+	if a.OIDC != nil {
+		if a.OIDC.ClientID == "" {
+			return fmt.Errorf("oidc requires a client_id")
+		}
+		if a.OIDC.Issuer == "" && (a.OIDC.AuthURL == "" || a.OIDC.TokenURL == "" || a.OIDC.UserinfoURL == "") {
+			return fmt.Errorf("oidc requires an issuer or auth_url, token_url and userinfo_url")
+		}
+		if len(a.OIDC.Admins) == 0 && len(a.OIDC.Readers) == 0 {
+			return fmt.Errorf("oidc requires admins or readers")
+		}
+	}
+	return nil
+}
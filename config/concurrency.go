@@ -0,0 +1,32 @@
+package config
+
+import "fmt"
+
+// DefaultMaxChecks keeps large monitor sets from opening a connection to
+// every page at once.
+const DefaultMaxChecks = 100
+
+// Concurrency limits how many checks run at the same time. Checks waiting for
+// a slot are reported as pending.
+type Concurrency struct {
+	// MaxChecks limits checks across all pages
+	MaxChecks int `yaml:"max_checks,omitempty"`
+	// MaxChecksPerHost limits checks of pages on the same host, 0 means no
+	// limit
+	MaxChecksPerHost int `yaml:"max_checks_per_host,omitempty"`
+}
+
+// Limit returns how many checks may run at the same time.
+func (c Concurrency) Limit() int {
+	if c.MaxChecks == 0 {
+		return DefaultMaxChecks
+	}
+	return c.MaxChecks
+}
+
+func (c Concurrency) validate() error {
+	if c.MaxChecks < 0 || c.MaxChecksPerHost < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
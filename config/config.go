@@ -2,8 +2,18 @@ package config
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/marshallku/statusy/jsonpath"
 	"gopkg.in/yaml.v2"
 )
 
@@ -12,20 +22,373 @@ type Config struct {
 	Timeout       int    `yaml:"timeout"`
 	Pages         []Page `yaml:"pages"`
 	CheckInterval int    `yaml:"check_interval"`
+	// DownInterval is how many seconds apart pages are checked while they
+	// are down, defaulting to CheckInterval
+	DownInterval int `yaml:"down_interval,omitempty"`
+	// Backoff slows down checks of pages that stay down
+	Backoff *Backoff `yaml:"backoff,omitempty"`
+	// Escalation is the default escalation policy for pages without their own
+	Escalation []EscalationStep `yaml:"escalation,omitempty"`
+	StatusPage StatusPage       `yaml:"status_page,omitempty"`
+	// Network holds the default network options for all pages
+	Network Network `yaml:"network,omitempty"`
+	// Transport tunes the connection pool shared by all pages
+	Transport Transport `yaml:"transport,omitempty"`
+	// Concurrency limits how many checks run at the same time
+	Concurrency Concurrency `yaml:"concurrency,omitempty"`
+	// MaxBodyBytes is the default limit on how much of a response is read
+	MaxBodyBytes int64 `yaml:"max_body_bytes,omitempty"`
+	// Server configures the listener of the web interface and API
+	Server Server         `yaml:"server,omitempty"`
+	Auth   Authentication `yaml:"auth,omitempty"`
+	// RunTimeout is how many seconds a check may take, defaulting to the
+	// interval the page is checked at
+	RunTimeout int `yaml:"run_timeout,omitempty"`
+	// ShutdownTimeout is how many seconds running checks and open requests
+	// are given to finish when stopping
+	ShutdownTimeout int `yaml:"shutdown_timeout,omitempty"`
+}
+
+// StatusPage configures the public, read-only status page.
+type StatusPage struct {
+	Title      string      `yaml:"title"`
+	Components []Component `yaml:"components"`
+}
+
+// Component groups pages under a name shown on the public status page.
+type Component struct {
+	Name string `yaml:"name"`
+	// Pages lists the IDs of the pages in the component
+	Pages []string `yaml:"pages"`
 }
 
 type Page struct {
-	URL           string   `yaml:"url"`
-	Status        int      `yaml:"status"`
-	TextToInclude string   `yaml:"text_to_include"`
-	Speed         int      `yaml:"speed"`
-	Request       *Request `yaml:"request,omitempty"`
+	// ID identifies the page in results, badges and the API. When omitted, it
+	// is derived from the request method, URL and body.
+	ID          string   `yaml:"id"`
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
+
+	// Type is either TypeHTTP, the default, or TypeSteps
+	Type    string   `yaml:"type,omitempty"`
+	URL     string   `yaml:"url"`
+	Request *Request `yaml:"request,omitempty"`
+	TLS     *TLS     `yaml:"tls,omitempty"`
+	Network *Network `yaml:"network,omitempty"`
+	// KeepAlive defaults to true, reusing connections between checks
+	KeepAlive *bool `yaml:"keep_alive,omitempty"`
+	// HTTP2 defaults to true, allowing HTTP/2 over TLS
+	HTTP2 *bool `yaml:"http2,omitempty"`
+	// HeadOnly sends a HEAD request, for pages whose body doesn't matter
+	HeadOnly bool `yaml:"head_only,omitempty"`
+	// Steps are the requests of a TypeSteps page, sent in order
+	Steps []Step `yaml:"steps,omitempty"`
+	// Variables are the initial variables available to steps
+	Variables map[string]string `yaml:"variables,omitempty"`
+
+	// FollowRedirects defaults to true unless only redirects are expected
+	FollowRedirects *bool `yaml:"follow_redirects,omitempty"`
+	MaxRedirects    int   `yaml:"max_redirects,omitempty"`
+	// FinalURL is the URL the page must end up on after following redirects
+	FinalURL string `yaml:"final_url,omitempty"`
+	// AllowInsecureRedirects allows redirects from HTTPS to HTTP
+	AllowInsecureRedirects bool `yaml:"allow_insecure_redirects,omitempty"`
+
+	Status        ExpectedStatus `yaml:"status"`
+	Speed         int            `yaml:"speed"`
+	TextToInclude string         `yaml:"text_to_include"`
+	TextToExclude StringList     `yaml:"text_to_exclude,omitempty"`
+	BodyRegex     string         `yaml:"body_regex,omitempty"`
+	MinBodySize   int64          `yaml:"min_body_size,omitempty"`
+	MaxBodySize   int64          `yaml:"max_body_size,omitempty"`
+	// BodyHash is the expected SHA-256 of the body, optionally prefixed with
+	// "sha256:"
+	BodyHash string `yaml:"body_hash,omitempty"`
+	// MaxBodyBytes limits how much of the response is read, overriding the
+	// global limit
+	MaxBodyBytes int64 `yaml:"max_body_bytes,omitempty"`
+	// Assertions are evaluated against the JSON response body
+	Assertions      []Assertion       `yaml:"assertions,omitempty"`
+	ResponseHeaders []HeaderAssertion `yaml:"response_headers,omitempty"`
+	// SecurityHeaders adds SecurityHeaderAssertions to ResponseHeaders
+	SecurityHeaders bool `yaml:"security_headers,omitempty"`
+
+	// Escalation overrides the global escalation policy for this page
+	Escalation []EscalationStep `yaml:"escalation,omitempty"`
+	// DownInterval and Backoff override the global ones for this page
+	DownInterval int      `yaml:"down_interval,omitempty"`
+	Backoff      *Backoff `yaml:"backoff,omitempty"`
+}
+
+// EscalationStep notifies an additional webhook once an incident has stayed
+// unacknowledged for After minutes.
+type EscalationStep struct {
+	After      int    `yaml:"after"`
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+func (e EscalationStep) Delay() time.Duration {
+	return time.Duration(e.After) * time.Minute
+}
+
+// StringList accepts either a single string or a list of strings.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var single string
+	if err := unmarshal(&single); err == nil {
+		*l = StringList{single}
+		return nil
+	}
+
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// ExpectedBodyHash returns the hex encoded SHA-256 the body must match.
+func (p Page) ExpectedBodyHash() string {
+	return strings.ToLower(strings.TrimPrefix(p.BodyHash, "sha256:"))
+}
+
+func (p Page) validateBody() error {
+	if p.BodyRegex != "" {
+		if _, err := regexp.Compile(p.BodyRegex); err != nil {
+			return fmt.Errorf("invalid body_regex: %w", err)
+		}
+	}
+	if p.MaxBodySize > 0 && p.MinBodySize > p.MaxBodySize {
+		return fmt.Errorf("min_body_size is larger than max_body_size")
+	}
+	if p.MaxBodyBytes < 0 {
+		return fmt.Errorf("max_body_bytes must not be negative")
+	}
+	if p.HeadOnly {
+		if p.Request != nil && p.Request.Method != "" && p.Request.Method != http.MethodHead {
+			return fmt.Errorf("head_only cannot be used with method %s", p.Request.Method)
+		}
+		if p.ChecksBody() {
+			return fmt.Errorf("head_only pages cannot check the response body")
+		}
+	}
+	if p.BodyHash != "" {
+		if hash, err := hex.DecodeString(p.ExpectedBodyHash()); err != nil || len(hash) != sha256.Size {
+			return fmt.Errorf("body_hash must be a hex encoded SHA-256")
+		}
+	}
+	return nil
+}
+
+// CheckDeadline returns how long a check of a page checked every interval
+// may take. It defaults to the interval, so that a hung check is given up
+// by the time the page is due again.
+func (c *Config) CheckDeadline(interval time.Duration) time.Duration {
+	if c.RunTimeout > 0 {
+		return time.Duration(c.RunTimeout) * time.Second
+	}
+	return interval
+}
+
+// DefaultShutdownTimeout leaves time to exit before Docker's default stop
+// timeout of 10 seconds kills the process.
+const DefaultShutdownTimeout = 8
+
+// GracePeriod returns how long stopping waits for running checks and open
+// requests.
+func (c *Config) GracePeriod() time.Duration {
+	if c.ShutdownTimeout > 0 {
+		return time.Duration(c.ShutdownTimeout) * time.Second
+	}
+	return DefaultShutdownTimeout * time.Second
+}
+
+// DefaultMaxBodyBytes is how much of a response is read when no limit is set.
+const DefaultMaxBodyBytes = 10 << 20
+
+// BodyLimit returns how many bytes of the page's response body are read.
+func (c *Config) BodyLimit(page Page) int64 {
+	if page.MaxBodyBytes > 0 {
+		return page.MaxBodyBytes
+	}
+	if c.MaxBodyBytes > 0 {
+		return c.MaxBodyBytes
+	}
+	return DefaultMaxBodyBytes
+}
+
+// ChecksBody reports whether any check of the page reads the response body.
+func (p Page) ChecksBody() bool {
+	return p.TextToInclude != "" || p.NeedsFullBody() || p.BodyRegex != ""
+}
+
+// NeedsFullBody reports whether a check of the page can only be evaluated
+// once the whole response body has been read. text_to_include and body_regex
+// are evaluated while the body is read instead.
+func (p Page) NeedsFullBody() bool {
+	return len(p.TextToExclude) > 0 || p.MinBodySize > 0 || p.MaxBodySize > 0 ||
+		p.BodyHash != "" || len(p.Assertions) > 0
+}
+
+// HeaderAssertion checks a response header. With only a name, the header
+// must be present.
+type HeaderAssertion struct {
+	Name   string `yaml:"name"`
+	Equals string `yaml:"equals,omitempty"`
+	Regex  string `yaml:"regex,omitempty"`
+	Absent bool   `yaml:"absent,omitempty"`
+}
+
+// SecurityHeaderAssertions is the preset enabled by security_headers.
+var SecurityHeaderAssertions = []HeaderAssertion{
+	{Name: "Strict-Transport-Security", Regex: `max-age=[1-9]\d*`},
+	{Name: "Content-Security-Policy"},
+	{Name: "X-Frame-Options", Regex: `(?i)^(deny|sameorigin)$`},
+}
+
+func (h HeaderAssertion) validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("response header assertion requires a name")
+	}
+	if h.Absent && (h.Equals != "" || h.Regex != "") {
+		return fmt.Errorf("header %s cannot be absent and have a value", h.Name)
+	}
+	if h.Regex != "" {
+		if _, err := regexp.Compile(h.Regex); err != nil {
+			return fmt.Errorf("invalid regex for header %s: %w", h.Name, err)
+		}
+	}
+	return nil
+}
+
+// HeaderAssertions returns the header assertions of the page, including the
+// security preset when enabled.
+func (p Page) HeaderAssertions() []HeaderAssertion {
+	if !p.SecurityHeaders {
+		return p.ResponseHeaders
+	}
+	return append(append([]HeaderAssertion{}, SecurityHeaderAssertions...), p.ResponseHeaders...)
+}
+
+// Assertion operators
+const (
+	OpEquals      = "equals"
+	OpNotEquals   = "not_equals"
+	OpContains    = "contains"
+	OpGreaterThan = "greater_than"
+	OpLessThan    = "less_than"
+	OpExists      = "exists"
+	OpNotExists   = "not_exists"
+	OpRegex       = "regex"
+)
+
+// Assertion checks the values selected by a JSONPath expression.
+type Assertion struct {
+	Path     string      `yaml:"path"`
+	Operator string      `yaml:"operator"`
+	Value    interface{} `yaml:"value"`
+}
+
+func (a Assertion) validate() error {
+	if _, err := jsonpath.Compile(a.Path); err != nil {
+		return err
+	}
+
+	switch a.Operator {
+	case OpEquals, OpNotEquals, OpContains, OpExists, OpNotExists:
+	case OpGreaterThan, OpLessThan:
+		if _, ok := ToFloat(a.Value); !ok {
+			return fmt.Errorf("%s requires a numeric value", a.Operator)
+		}
+	case OpRegex:
+		pattern, ok := a.Value.(string)
+		if !ok {
+			return fmt.Errorf("regex requires a string value")
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown operator %q", a.Operator)
+	}
+
+	return nil
+}
+
+// ToFloat converts numbers decoded from YAML or JSON to float64.
+func ToFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
 }
 
 type Request struct {
 	Method  string            `yaml:"method"`
 	Headers map[string]string `yaml:"headers"`
 	Body    string            `yaml:"body"`
+	Auth    *Auth             `yaml:"auth,omitempty"`
+}
+
+// Auth types
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthOAuth2 = "oauth2"
+)
+
+// Auth authenticates requests with basic auth, a static bearer token, or a
+// token obtained with the OAuth2 client credentials flow.
+type Auth struct {
+	Type string `yaml:"type"`
+
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	Token string `yaml:"token,omitempty"`
+
+	TokenURL     string   `yaml:"token_url,omitempty"`
+	ClientID     string   `yaml:"client_id,omitempty"`
+	ClientSecret string   `yaml:"client_secret,omitempty"`
+	Scopes       []string `yaml:"scopes,omitempty"`
+	// TLS applies to the token endpoint, which is verified with the default
+	// options otherwise. The page's own TLS options never apply to it.
+	TLS *TLS `yaml:"tls,omitempty"`
+}
+
+func (a Auth) validate() error {
+	switch a.Type {
+	case AuthBasic:
+		if a.Username == "" {
+			return fmt.Errorf("basic auth requires a username")
+		}
+	case AuthBearer:
+		if a.Token == "" {
+			return fmt.Errorf("bearer auth requires a token")
+		}
+	case AuthOAuth2:
+		if a.TokenURL == "" || a.ClientID == "" {
+			return fmt.Errorf("oauth2 auth requires token_url and client_id")
+		}
+		if a.TLS != nil {
+			if err := a.TLS.validate(); err != nil {
+				return fmt.Errorf("token_url: %w", err)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown auth type %q", a.Type)
+	}
+	return nil
 }
 
 func LoadConfig(filename string) (*Config, error) {
@@ -46,5 +409,198 @@ func LoadConfig(filename string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.Server.validate(); err != nil {
+		return nil, fmt.Errorf("server: %w", err)
+	}
+	if err := config.Auth.validate(); err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	if config.MaxBodyBytes < 0 {
+		return nil, fmt.Errorf("max_body_bytes must not be negative")
+	}
+	if err := validateSchedule(config.DownInterval, config.Backoff); err != nil {
+		return nil, err
+	}
+	if config.RunTimeout < 0 {
+		return nil, fmt.Errorf("run_timeout must not be negative")
+	}
+	if config.ShutdownTimeout < 0 {
+		return nil, fmt.Errorf("shutdown_timeout must not be negative")
+	}
+	if err := config.Network.validate(); err != nil {
+		return nil, fmt.Errorf("network: %w", err)
+	}
+	if err := config.Transport.validate(); err != nil {
+		return nil, fmt.Errorf("transport: %w", err)
+	}
+	if err := config.Concurrency.validate(); err != nil {
+		return nil, fmt.Errorf("concurrency: %w", err)
+	}
+	if err := validateEscalation(config.Escalation); err != nil {
+		return nil, fmt.Errorf("escalation: %w", err)
+	}
+
+	ids := make(map[string]bool, len(config.Pages))
+	for i := range config.Pages {
+		page := &config.Pages[i]
+
+		if page.ID == "" {
+			page.ID = page.defaultID()
+		}
+		if page.Name == "" {
+			page.Name = page.URL
+		}
+		if page.Name == "" {
+			page.Name = page.ID
+		}
+		if ids[page.ID] {
+			return nil, fmt.Errorf("duplicate page id %q, set a unique id on each page", page.ID)
+		}
+		ids[page.ID] = true
+
+		if err := page.validate(); err != nil {
+			return nil, fmt.Errorf("page %q: %w", page.ID, err)
+		}
+		if limit := config.BodyLimit(*page); page.MinBodySize > limit || page.MaxBodySize > limit {
+			return nil, fmt.Errorf("page %q: body size bounds cannot exceed max_body_bytes (%d)", page.ID, limit)
+		}
+		if err := config.NetworkFor(*page).validateResolve(); err != nil {
+			return nil, fmt.Errorf("page %q: network: %w", page.ID, err)
+		}
+		if backoff := config.BackoffFor(*page); backoff != nil && backoff.MaxInterval > 0 && backoff.MaxIntervalDuration() < config.DownIntervalFor(*page) {
+			return nil, fmt.Errorf("page %q: backoff max_interval cannot be shorter than the down interval (%s)", page.ID, config.DownIntervalFor(*page))
+		}
+	}
+
+	for _, component := range config.StatusPage.Components {
+		for _, id := range component.Pages {
+			if !ids[id] {
+				return nil, fmt.Errorf("status_page: component %q: unknown page %q", component.Name, id)
+			}
+		}
+	}
+
 	return &config, nil
 }
+
+// defaultID derives a stable ID from what the page requests, so that pages
+// sharing a URL but using different methods or bodies stay distinct.
+func (p Page) defaultID() string {
+	hash := sha1.New()
+	p.writeIdentity(hash)
+	for _, step := range p.Steps {
+		step.writeIdentity(hash)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+func (p Page) writeIdentity(w io.Writer) {
+	method, body := "GET", ""
+	if p.Request != nil {
+		method, body = p.Request.Method, p.Request.Body
+	}
+	io.WriteString(w, method+" "+p.URL+"\n"+body)
+}
+
+// validate checks the options of the page and of its steps.
+func (p Page) validate() error {
+	if p.Request != nil && p.Request.Auth != nil {
+		if err := p.Request.Auth.validate(); err != nil {
+			return err
+		}
+	}
+	if p.TLS != nil {
+		if err := p.TLS.validate(); err != nil {
+			return err
+		}
+	}
+	if p.Network != nil {
+		if err := p.Network.validate(); err != nil {
+			return err
+		}
+	}
+	if err := p.validateBody(); err != nil {
+		return err
+	}
+	if err := validateSchedule(p.DownInterval, p.Backoff); err != nil {
+		return err
+	}
+	if err := validateEscalation(p.Escalation); err != nil {
+		return fmt.Errorf("escalation: %w", err)
+	}
+	for _, header := range p.ResponseHeaders {
+		if err := header.validate(); err != nil {
+			return err
+		}
+	}
+	for _, assertion := range p.Assertions {
+		if err := assertion.validate(); err != nil {
+			return fmt.Errorf("invalid assertion on %q: %w", assertion.Path, err)
+		}
+	}
+	return p.validateSteps()
+}
+
+// DefaultMaxRedirects matches the limit of the default http.Client.
+const DefaultMaxRedirects = 10
+
+// ShouldFollowRedirects reports whether redirects are followed for the page.
+func (p Page) ShouldFollowRedirects() bool {
+	if p.Status.ExpectsRedirect() {
+		return false
+	}
+	return p.FollowRedirects == nil || *p.FollowRedirects
+}
+
+// RedirectLimit returns the maximum number of redirects to follow.
+func (p Page) RedirectLimit() int {
+	if p.MaxRedirects > 0 {
+		return p.MaxRedirects
+	}
+	return DefaultMaxRedirects
+}
+
+// HasTag reports whether the page is tagged with tag.
+func (p Page) HasTag(tag string) bool {
+	for _, t := range p.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// FindPage returns the page with the given ID.
+func (c *Config) FindPage(id string) (Page, bool) {
+	for _, page := range c.Pages {
+		if page.ID == id {
+			return page, true
+		}
+	}
+	return Page{}, false
+}
+
+// EscalationPolicy returns the escalation steps that apply to the page.
+func (c *Config) EscalationPolicy(page Page) []EscalationStep {
+	if len(page.Escalation) > 0 {
+		return page.Escalation
+	}
+	return c.Escalation
+}
+
+// validateEscalation checks that every step has a webhook and that the steps
+// are listed in the order they fire.
+func validateEscalation(steps []EscalationStep) error {
+	for i, step := range steps {
+		if step.WebhookURL == "" {
+			return fmt.Errorf("step %d requires a webhook_url", i+1)
+		}
+		if step.After < 0 {
+			return fmt.Errorf("step %d: after must not be negative", i+1)
+		}
+		if i > 0 && step.After <= steps[i-1].After {
+			return fmt.Errorf("step %d: after must be greater than the previous step's (%d)", i+1, steps[i-1].After)
+		}
+	}
+	return nil
+}
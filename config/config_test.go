@@ -0,0 +1,542 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestLoadConfig_PageIdentity(t *testing.T) {
+	filename := writeConfig(t, `
+pages:
+  - id: home
+    name: Home page
+    description: Landing page
+    tags: [web, public]
+    url: https://example.com
+  - url: https://example.com/api
+  - url: https://example.com/api
+    request:
+      method: POST
+      body: '{}'
+`)
+
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "home", cfg.Pages[0].ID)
+	assert.Equal(t, "Home page", cfg.Pages[0].Name)
+	assert.True(t, cfg.Pages[0].HasTag("web"))
+	assert.False(t, cfg.Pages[0].HasTag("internal"))
+
+	assert.NotEmpty(t, cfg.Pages[1].ID)
+	assert.Equal(t, "https://example.com/api", cfg.Pages[1].Name)
+	assert.NotEqual(t, cfg.Pages[1].ID, cfg.Pages[2].ID)
+
+	again, err := LoadConfig(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, cfg.Pages[1].ID, again.Pages[1].ID, "derived IDs must be stable")
+
+	page, ok := cfg.FindPage("home")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com", page.URL)
+}
+
+func TestLoadConfig_DuplicateIDs(t *testing.T) {
+	filename := writeConfig(t, `
+pages:
+  - url: https://example.com
+  - url: https://example.com
+`)
+
+	_, err := LoadConfig(filename)
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_InvalidAssertion(t *testing.T) {
+	filename := writeConfig(t, `
+pages:
+  - url: https://example.com
+    assertions:
+      - path: $.latency
+        operator: greater_than
+        value: fast
+`)
+
+	_, err := LoadConfig(filename)
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_TextToExclude(t *testing.T) {
+	filename := writeConfig(t, `
+pages:
+  - id: single
+    url: https://example.com
+    text_to_exclude: maintenance
+  - id: list
+    url: https://example.com/api
+    text_to_exclude: [maintenance, Internal Server Error]
+`)
+
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, StringList{"maintenance"}, cfg.Pages[0].TextToExclude)
+	assert.Equal(t, StringList{"maintenance", "Internal Server Error"}, cfg.Pages[1].TextToExclude)
+}
+
+func TestLoadConfig_Steps(t *testing.T) {
+	filename := writeConfig(t, `
+pages:
+  - id: checkout
+    type: steps
+    steps:
+      - name: login
+        url: https://example.com/login
+        request:
+          method: POST
+          body: '{"user":"jane"}'
+        capture:
+          - name: token
+            jsonpath: $.token
+      - name: orders
+        url: https://example.com/orders
+        request:
+          method: GET
+          headers:
+            Authorization: Bearer ${token}
+        status: 2xx
+`)
+
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+
+	page := cfg.Pages[0]
+	assert.Equal(t, "checkout", page.Name)
+	assert.Len(t, page.Steps, 2)
+	assert.Equal(t, "login", page.Steps[0].Name)
+	assert.Equal(t, "$.token", page.Steps[0].Capture[0].JSONPath)
+	assert.True(t, page.Steps[1].Status.Matches(204))
+}
+
+func TestLoadConfig_InvalidSteps(t *testing.T) {
+	for _, content := range []string{
+		"pages:\n  - type: steps\n",
+		"pages:\n  - url: https://example.com\n    steps:\n      - url: https://example.com\n",
+		"pages:\n  - type: steps\n    steps:\n      - url: https://example.com\n        capture:\n          - name: token\n",
+	} {
+		_, err := LoadConfig(writeConfig(t, content))
+		assert.Error(t, err, content)
+	}
+}
+
+func TestLoadConfig_StatusPage(t *testing.T) {
+	filename := writeConfig(t, `
+pages:
+  - id: web
+    url: https://example.com
+status_page:
+  components:
+    - name: Website
+      pages: [web]
+`)
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web"}, cfg.StatusPage.Components[0].Pages)
+
+	// A typo in a page ID would otherwise hide the page from the status page
+	filename = writeConfig(t, `
+pages:
+  - id: web
+    url: https://example.com
+status_page:
+  components:
+    - name: Website
+      pages: [web, wbe]
+`)
+	_, err = LoadConfig(filename)
+	assert.ErrorContains(t, err, `unknown page "wbe"`)
+}
+
+func TestLoadConfig_Escalation(t *testing.T) {
+	filename := writeConfig(t, `
+escalation:
+  - after: 0
+    webhook_url: https://hooks.example.com/first
+  - after: 15
+    webhook_url: https://hooks.example.com/second
+pages:
+  - url: https://example.com
+`)
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Escalation, 2)
+
+	tests := []struct {
+		name     string
+		steps    string
+		expected string
+	}{
+		{name: "missing webhook", steps: "[{after: 5}]", expected: "webhook_url"},
+		{name: "negative delay", steps: "[{after: -5, webhook_url: https://hooks.example.com}]", expected: "negative"},
+		{name: "same delay", steps: "[{after: 5, webhook_url: https://hooks.example.com/a}, {after: 5, webhook_url: https://hooks.example.com/b}]", expected: "step 2"},
+		{name: "out of order", steps: "[{after: 15, webhook_url: https://hooks.example.com/a}, {after: 5, webhook_url: https://hooks.example.com/b}]", expected: "step 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, "escalation: "+tt.steps+"\npages:\n  - url: https://example.com\n"))
+			assert.ErrorContains(t, err, "escalation: ")
+			assert.ErrorContains(t, err, tt.expected)
+
+			_, err = LoadConfig(writeConfig(t, "pages:\n  - url: https://example.com\n    id: web\n    escalation: "+tt.steps+"\n"))
+			assert.ErrorContains(t, err, `page "web": escalation: `)
+			assert.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+func TestLoadConfig_InvalidAuth(t *testing.T) {
+	filename := writeConfig(t, `
+pages:
+  - url: https://example.com
+    request:
+      method: GET
+      auth:
+        type: oauth2
+        client_id: statusy
+`)
+
+	_, err := LoadConfig(filename)
+	assert.Error(t, err)
+
+	filename = writeConfig(t, `
+pages:
+  - url: https://example.com
+    request:
+      method: GET
+      auth:
+        type: oauth2
+        token_url: https://auth.example.com/token
+        client_id: statusy
+        tls:
+          cert_file: client.crt
+`)
+
+	_, err = LoadConfig(filename)
+	assert.ErrorContains(t, err, "token_url")
+}
+
+func TestLoadConfig_InvalidTLS(t *testing.T) {
+	tests := []struct {
+		name string
+		tls  string
+	}{
+		{name: "cert without key", tls: "cert_file: client.crt"},
+		{name: "unknown version", tls: "min_version: \"2.0\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeConfig(t, `
+pages:
+  - url: https://example.com
+    tls:
+      `+tt.tls+`
+`)
+			_, err := LoadConfig(filename)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadConfig_Network(t *testing.T) {
+	filename := writeConfig(t, `
+network:
+  proxy: socks5://127.0.0.1:1080
+  ip_version: 4
+pages:
+  - url: https://example.com
+  - url: https://example.org
+    network:
+      ip_version: 6
+      source_address: "2001:db8::2"
+`)
+
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+
+	network := cfg.NetworkFor(cfg.Pages[0])
+	assert.Equal(t, "socks5://127.0.0.1:1080", network.Proxy)
+	assert.Equal(t, "tcp4", network.Network())
+
+	network = cfg.NetworkFor(cfg.Pages[1])
+	assert.Equal(t, "socks5://127.0.0.1:1080", network.Proxy)
+	assert.Equal(t, "tcp6", network.Network())
+	assert.Equal(t, "2001:db8::2", network.SourceAddress)
+}
+
+func TestLoadConfig_InvalidNetwork(t *testing.T) {
+	tests := []struct {
+		name    string
+		network string
+	}{
+		{name: "unsupported proxy", network: "proxy: ftp://127.0.0.1"},
+		{name: "unknown IP version", network: "ip_version: 5"},
+		{name: "invalid source address", network: "source_address: localhost"},
+		{name: "invalid pinned address", network: "resolve: {example.com: example.net}"},
+		{name: "pinned address with proxy", network: "{proxy: http://127.0.0.1:3128, resolve: {example.com: 10.0.0.1}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeConfig(t, `
+pages:
+  - url: https://example.com
+    network:
+      `+tt.network+`
+`)
+			_, err := LoadConfig(filename)
+			assert.Error(t, err)
+		})
+	}
+
+	// A page can't pin addresses while inheriting the global proxy
+	_, err := LoadConfig(writeConfig(t, `
+network:
+  proxy: http://127.0.0.1:3128
+pages:
+  - url: https://example.com
+    network:
+      resolve:
+        example.com: 10.0.0.1
+`))
+	assert.ErrorContains(t, err, "resolve")
+}
+
+func TestLoadConfig_Transport(t *testing.T) {
+	filename := writeConfig(t, `
+transport:
+  max_idle_conns_per_host: 4
+pages:
+  - url: https://example.com
+    keep_alive: false
+    http2: false
+  - url: https://example.org
+`)
+
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+
+	total, perHost := cfg.Transport.IdleConns()
+	assert.Equal(t, DefaultMaxIdleConns, total)
+	assert.Equal(t, 4, perHost)
+	assert.False(t, cfg.Pages[0].KeepAliveEnabled())
+	assert.False(t, cfg.Pages[0].HTTP2Enabled())
+	assert.True(t, cfg.Pages[1].KeepAliveEnabled())
+	assert.True(t, cfg.Pages[1].HTTP2Enabled())
+
+	filename = writeConfig(t, `
+transport:
+  max_conns_per_host: -1
+pages:
+  - url: https://example.com
+`)
+	_, err = LoadConfig(filename)
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_Body(t *testing.T) {
+	filename := writeConfig(t, `
+max_body_bytes: 2048
+pages:
+  - url: https://example.com/health
+    head_only: true
+  - url: https://example.com/download
+    max_body_bytes: 4096
+    max_body_size: 4096
+`)
+
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2048), cfg.BodyLimit(cfg.Pages[0]))
+	assert.Equal(t, int64(4096), cfg.BodyLimit(cfg.Pages[1]))
+	assert.Equal(t, int64(DefaultMaxBodyBytes), (&Config{}).BodyLimit(Page{}))
+
+	invalid := []struct {
+		name string
+		page string
+	}{
+		{name: "head only with body check", page: "head_only: true\n    text_to_include: ok"},
+		{name: "head only with method", page: "head_only: true\n    request:\n      method: POST"},
+		{name: "size above limit", page: "max_body_size: 4096"},
+	}
+
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeConfig(t, `
+max_body_bytes: 2048
+pages:
+  - url: https://example.com
+    `+tt.page+`
+`)
+			_, err := LoadConfig(filename)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestServer_Prefix(t *testing.T) {
+	tests := []struct {
+		basePath string
+		prefix   string
+	}{
+		{"", ""},
+		{"/", ""},
+		{"status", "/status"},
+		{"/status/", "/status"},
+		{"/tools/status", "/tools/status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.basePath, func(t *testing.T) {
+			assert.Equal(t, tt.prefix, Server{BasePath: tt.basePath}.Prefix())
+		})
+	}
+}
+
+func TestLoadConfig_InvalidServer(t *testing.T) {
+	filename := writeConfig(t, `
+server:
+  address: ":8443"
+  cert_file: server.crt
+pages:
+  - url: https://example.com
+`)
+	_, err := LoadConfig(filename)
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_InvalidAuthentication(t *testing.T) {
+	tests := []struct {
+		name string
+		auth string
+	}{
+		{"missing token", "tokens:\n    - name: grafana"},
+		{"unknown role", "tokens:\n    - name: grafana\n      token: abc\n      role: owner"},
+		{"plain text password", "users:\n    - username: jane\n      password_hash: secret"},
+		{"oidc without client", "oidc:\n    issuer: https://idp.example.com"},
+		{"oidc without endpoints", "oidc:\n    client_id: statusy"},
+		{"oidc without admins or readers", "oidc:\n    issuer: https://idp.example.com\n    client_id: statusy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := writeConfig(t, "auth:\n  "+tt.auth+"\npages:\n  - url: https://example.com\n")
+			_, err := LoadConfig(filename)
+			assert.Error(t, err)
+		})
+	}
+
+	filename := writeConfig(t, `
+auth:
+  tokens:
+    - name: grafana
+      token: abc
+      role: read
+  users:
+    - username: jane
+      password_hash: $2a$10$7EqJtq98hPqEX7fNZaFWoOhi5BWX4Z0AMO3MrSc4a9yq9k8ZEn5xG
+      role: admin
+  oidc:
+    issuer: https://idp.example.com
+    client_id: statusy
+    readers: [john@example.com]
+pages:
+  - url: https://example.com
+`)
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+	assert.True(t, cfg.Auth.Enabled())
+}
+
+func TestLoadConfig_Concurrency(t *testing.T) {
+	cfg, err := LoadConfig(writeConfig(t, "concurrency:\n  max_checks_per_host: 4\npages:\n  - url: https://example.com\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultMaxChecks, cfg.Concurrency.Limit())
+	assert.Equal(t, 4, cfg.Concurrency.MaxChecksPerHost)
+
+	cfg, err = LoadConfig(writeConfig(t, "concurrency:\n  max_checks: 10\npages:\n  - url: https://example.com\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 10, cfg.Concurrency.Limit())
+
+	_, err = LoadConfig(writeConfig(t, "concurrency:\n  max_checks: -1\npages:\n  - url: https://example.com\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadConfig_Schedule(t *testing.T) {
+	filename := writeConfig(t, `
+check_interval: 60
+down_interval: 10
+backoff:
+  after: 300
+  max_interval: 600
+pages:
+  - url: https://example.com
+  - url: https://example.org
+    down_interval: 5
+    backoff:
+      multiplier: 1.5
+      max_interval: 120
+`)
+	cfg, err := LoadConfig(filename)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 60*time.Second, cfg.Interval())
+	assert.Equal(t, 10*time.Second, cfg.DownIntervalFor(cfg.Pages[0]))
+	assert.Equal(t, 5*time.Second, cfg.DownIntervalFor(cfg.Pages[1]))
+	assert.Equal(t, 5*time.Minute, cfg.BackoffFor(cfg.Pages[0]).Delay())
+	assert.Equal(t, 20*time.Second, cfg.BackoffFor(cfg.Pages[0]).Next(10*time.Second))
+	assert.Equal(t, 600*time.Second, cfg.BackoffFor(cfg.Pages[0]).Next(400*time.Second))
+	assert.Equal(t, 15*time.Second, cfg.BackoffFor(cfg.Pages[1]).Next(10*time.Second))
+
+	for _, content := range []string{
+		"down_interval: -1\npages:\n  - url: https://example.com\n",
+		"backoff:\n  after: 60\npages:\n  - url: https://example.com\n",
+		"pages:\n  - url: https://example.com\n    backoff:\n      multiplier: 0.5\n      max_interval: 60\n",
+		// max_interval must not be shorter than the down interval
+		"check_interval: 60\nbackoff:\n  max_interval: 30\npages:\n  - url: https://example.com\n",
+		"backoff:\n  max_interval: 60\npages:\n  - url: https://example.com\n    down_interval: 120\n",
+	} {
+		_, err := LoadConfig(writeConfig(t, content))
+		assert.Error(t, err, content)
+	}
+}
+
+func TestConfig_CheckDeadline(t *testing.T) {
+	assert.Equal(t, 60*time.Second, (&Config{}).CheckDeadline(time.Minute))
+	assert.Equal(t, 5*time.Second, (&Config{CheckInterval: 60}).CheckDeadline(5*time.Second))
+	assert.Equal(t, 20*time.Second, (&Config{CheckInterval: 60, RunTimeout: 20}).CheckDeadline(5*time.Second))
+
+	_, err := LoadConfig(writeConfig(t, "run_timeout: -1\npages:\n  - url: https://example.com\n"))
+	assert.Error(t, err)
+}
+
+func TestConfig_GracePeriod(t *testing.T) {
+	assert.Equal(t, DefaultShutdownTimeout*time.Second, (&Config{}).GracePeriod())
+	assert.Equal(t, 30*time.Second, (&Config{ShutdownTimeout: 30}).GracePeriod())
+
+	_, err := LoadConfig(writeConfig(t, "shutdown_timeout: -1\npages:\n  - url: https://example.com\n"))
+	assert.Error(t, err)
+}
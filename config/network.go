@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Network controls how connections to a page are made.
+type Network struct {
+	// Proxy is an http, https or socks5 proxy URL. When empty, the proxy is
+	// taken from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string `yaml:"proxy,omitempty"`
+	// IPVersion forces IPv4 or IPv6 when set to 4 or 6
+	IPVersion int `yaml:"ip_version,omitempty"`
+	// SourceAddress is the local IP address connections are made from
+	SourceAddress string `yaml:"source_address,omitempty"`
+	// Resolve pins host names to IP addresses, like curl's --resolve. Pages
+	// with pinned addresses connect directly, without a proxy.
+	Resolve map[string]string `yaml:"resolve,omitempty"`
+}
+
+var proxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// IsZero reports whether no option is set.
+func (n Network) IsZero() bool {
+	return n.Proxy == "" && n.IPVersion == 0 && n.SourceAddress == "" && len(n.Resolve) == 0
+}
+
+// ProxyURL returns the parsed proxy URL, or nil when no proxy is set.
+func (n Network) ProxyURL() (*url.URL, error) {
+	if n.Proxy == "" {
+		return nil, nil
+	}
+	proxy, err := url.Parse(n.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	if !proxySchemes[proxy.Scheme] {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+	}
+	return proxy, nil
+}
+
+// Network returns the dial network for TCP connections, e.g. "tcp4".
+func (n Network) Network() string {
+	switch n.IPVersion {
+	case 4:
+		return "tcp4"
+	case 6:
+		return "tcp6"
+	default:
+		return "tcp"
+	}
+}
+
+func (n Network) validate() error {
+	if _, err := n.ProxyURL(); err != nil {
+		return err
+	}
+	if n.IPVersion != 0 && n.IPVersion != 4 && n.IPVersion != 6 {
+		return fmt.Errorf("ip_version must be 4 or 6")
+	}
+	if n.SourceAddress != "" && net.ParseIP(n.SourceAddress) == nil {
+		return fmt.Errorf("invalid source_address %q", n.SourceAddress)
+	}
+	for host, address := range n.Resolve {
+		if net.ParseIP(address) == nil {
+			return fmt.Errorf("invalid address %q for %s", address, host)
+		}
+	}
+	return n.validateResolve()
+}
+
+// validateResolve rejects pinned addresses together with a proxy, which
+// would resolve the target host rather than the pinned one.
+func (n Network) validateResolve() error {
+	if n.Proxy != "" && len(n.Resolve) > 0 {
+		return fmt.Errorf("resolve can't be combined with a proxy")
+	}
+	return nil
+}
+
+// NetworkFor returns the network options of page. Options the page doesn't
+// set fall back to the global ones.
+func (c *Config) NetworkFor(page Page) Network {
+	network := c.Network
+	if page.Network == nil {
+		return network
+	}
+	if page.Network.Proxy != "" {
+		network.Proxy = page.Network.Proxy
+	}
+	if page.Network.IPVersion != 0 {
+		network.IPVersion = page.Network.IPVersion
+	}
+	if page.Network.SourceAddress != "" {
+		network.SourceAddress = page.Network.SourceAddress
+	}
+	if len(page.Network.Resolve) > 0 {
+		network.Resolve = page.Network.Resolve
+	}
+	return network
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultCheckInterval is used when check_interval isn't set
+	DefaultCheckInterval = 60
+	// DefaultBackoffMultiplier doubles the interval after every failed check
+	DefaultBackoffMultiplier = 2
+)
+
+// Backoff slows down checks of a page that has been down for a while, so
+// that a dead service isn't hammered. Once the page has been down for After
+// seconds, the interval grows by Multiplier after every failed check, up to
+// MaxInterval seconds.
+type Backoff struct {
+	After       int     `yaml:"after,omitempty"`
+	Multiplier  float64 `yaml:"multiplier,omitempty"`
+	MaxInterval int     `yaml:"max_interval"`
+}
+
+// Delay returns how long a page must be down before backing off.
+func (b Backoff) Delay() time.Duration {
+	return time.Duration(b.After) * time.Second
+}
+
+// MaxIntervalDuration returns the longest interval to back off to.
+func (b Backoff) MaxIntervalDuration() time.Duration {
+	return time.Duration(b.MaxInterval) * time.Second
+}
+
+// Next returns the interval following interval.
+func (b Backoff) Next(interval time.Duration) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier == 0 {
+		multiplier = DefaultBackoffMultiplier
+	}
+	return min(time.Duration(float64(interval)*multiplier), b.MaxIntervalDuration())
+}
+
+func (b Backoff) validate() error {
+	if b.After < 0 {
+		return fmt.Errorf("backoff after must not be negative")
+	}
+	if b.Multiplier != 0 && b.Multiplier < 1 {
+		return fmt.Errorf("backoff multiplier must be at least 1")
+	}
+	if b.MaxInterval <= 0 {
+		return fmt.Errorf("backoff requires a max_interval")
+	}
+	return nil
+}
+
+// Interval returns how often pages that are up are checked.
+func (c *Config) Interval() time.Duration {
+	if c.CheckInterval > 0 {
+		return time.Duration(c.CheckInterval) * time.Second
+	}
+	return DefaultCheckInterval * time.Second
+}
+
+// DownIntervalFor returns how often the page is checked while it is down.
+func (c *Config) DownIntervalFor(page Page) time.Duration {
+	switch {
+	case page.DownInterval > 0:
+		return time.Duration(page.DownInterval) * time.Second
+	case c.DownInterval > 0:
+		return time.Duration(c.DownInterval) * time.Second
+	}
+	return c.Interval()
+}
+
+// BackoffFor returns the backoff that applies to the page, if any.
+func (c *Config) BackoffFor(page Page) *Backoff {
+	if page.Backoff != nil {
+		return page.Backoff
+	}
+	return c.Backoff
+}
+
+func validateSchedule(downInterval int, backoff *Backoff) error {
+	if downInterval < 0 {
+		return fmt.Errorf("down_interval must not be negative")
+	}
+	if backoff != nil {
+		return backoff.validate()
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultAddress is where the web interface listens when no address is set.
+const DefaultAddress = ":8080"
+
+// Server configures how the web interface and API are served.
+type Server struct {
+	// Address is the TCP address to listen on, e.g. ":8080" or "127.0.0.1:80"
+	Address string `yaml:"address,omitempty"`
+	// Socket is the path of a Unix socket to listen on instead of Address
+	Socket string `yaml:"socket,omitempty"`
+	// CertFile and KeyFile enable HTTPS. They are reloaded when they change.
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// BasePath serves everything under a prefix, e.g. "/status"
+	BasePath string `yaml:"base_path,omitempty"`
+	// AllowedOrigins may open WebSocket connections in addition to the
+	// server's own origin, e.g. "https://dashboard.example.com"
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+}
+
+// ListenAddress returns the TCP address to listen on.
+func (s Server) ListenAddress() string {
+	if s.Address == "" {
+		return DefaultAddress
+	}
+	return s.Address
+}
+
+// TLS reports whether the server terminates TLS itself.
+func (s Server) TLS() bool {
+	return s.CertFile != ""
+}
+
+// Prefix returns the base path with a leading slash and no trailing slash,
+// or an empty string when everything is served from the root.
+func (s Server) Prefix() string {
+	prefix := strings.Trim(s.BasePath, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+func (s Server) validate() error {
+	if (s.CertFile == "") != (s.KeyFile == "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if s.Socket != "" && s.Address != "" {
+		return fmt.Errorf("address and socket cannot be set together")
+	}
+	return nil
+}
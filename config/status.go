@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusRange is an inclusive range of HTTP status codes.
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+func (r StatusRange) String() string {
+	switch {
+	case r.Min == r.Max:
+		return strconv.Itoa(r.Min)
+	case r.Min%100 == 0 && r.Max == r.Min+99:
+		return fmt.Sprintf("%dxx", r.Min/100)
+	default:
+		return fmt.Sprintf("%d-%d", r.Min, r.Max)
+	}
+}
+
+// ExpectedStatus is the set of status codes a page may answer with. In YAML it
+// is written as a code (200), a class ("2xx"), a range ("200-299"), "redirect"
+// for any 3xx, or a list of those. An empty ExpectedStatus expects 200.
+type ExpectedStatus []StatusRange
+
+// ExpectStatus builds an ExpectedStatus accepting the given codes.
+func ExpectStatus(codes ...int) ExpectedStatus {
+	status := make(ExpectedStatus, 0, len(codes))
+	for _, code := range codes {
+		status = append(status, StatusRange{Min: code, Max: code})
+	}
+	return status
+}
+
+func (s ExpectedStatus) effective() ExpectedStatus {
+	if len(s) == 0 {
+		return ExpectStatus(200)
+	}
+	return s
+}
+
+// Matches reports whether code is one of the expected status codes.
+func (s ExpectedStatus) Matches(code int) bool {
+	for _, r := range s.effective() {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectsRedirect reports whether only redirects are expected, in which case
+// redirects must not be followed.
+func (s ExpectedStatus) ExpectsRedirect() bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, r := range s {
+		if r.Min < 300 || r.Max > 399 {
+			return false
+		}
+	}
+	return true
+}
+
+func (s ExpectedStatus) String() string {
+	ranges := make([]string, 0, len(s))
+	for _, r := range s.effective() {
+		ranges = append(ranges, r.String())
+	}
+	return strings.Join(ranges, ", ")
+}
+
+func (s *ExpectedStatus) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var list []interface{}
+	if err := unmarshal(&list); err != nil {
+		var single interface{}
+		if err := unmarshal(&single); err != nil {
+			return err
+		}
+		list = []interface{}{single}
+	}
+
+	status := make(ExpectedStatus, 0, len(list))
+	for _, value := range list {
+		r, err := parseStatusRange(fmt.Sprint(value))
+		if err != nil {
+			return err
+		}
+		status = append(status, r)
+	}
+
+	*s = status
+	return nil
+}
+
+func parseStatusRange(value string) (StatusRange, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	var r StatusRange
+	switch {
+	case value == "redirect":
+		r = StatusRange{Min: 300, Max: 399}
+	case len(value) == 3 && strings.HasSuffix(value, "xx"):
+		class, err := strconv.Atoi(value[:1])
+		if err != nil {
+			return r, fmt.Errorf("invalid status %q", value)
+		}
+		r = StatusRange{Min: class * 100, Max: class*100 + 99}
+	case strings.Contains(value, "-"):
+		from, to, _ := strings.Cut(value, "-")
+		low, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return r, fmt.Errorf("invalid status %q", value)
+		}
+		high, err := strconv.Atoi(strings.TrimSpace(to))
+		if err != nil {
+			return r, fmt.Errorf("invalid status %q", value)
+		}
+		r = StatusRange{Min: low, Max: high}
+	default:
+		code, err := strconv.Atoi(value)
+		if err != nil {
+			return r, fmt.Errorf("invalid status %q", value)
+		}
+		r = StatusRange{Min: code, Max: code}
+	}
+
+	if r.Min < 100 || r.Max > 599 || r.Min > r.Max {
+		return r, fmt.Errorf("invalid status %q", value)
+	}
+	return r, nil
+}
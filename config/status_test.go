@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestExpectedStatus_UnmarshalYAML(t *testing.T) {
+	tests := []struct {
+		yaml     string
+		expected ExpectedStatus
+		str      string
+		redirect bool
+	}{
+		{"status: 201", ExpectedStatus{{201, 201}}, "201", false},
+		{"status: [200, 204]", ExpectedStatus{{200, 200}, {204, 204}}, "200, 204", false},
+		{`status: "2xx"`, ExpectedStatus{{200, 299}}, "2xx", false},
+		{`status: "200-206"`, ExpectedStatus{{200, 206}}, "200-206", false},
+		{"status: redirect", ExpectedStatus{{300, 399}}, "3xx", true},
+		{"status: [301, 302]", ExpectedStatus{{301, 301}, {302, 302}}, "301, 302", true},
+		{"status: [200, 3xx]", ExpectedStatus{{200, 200}, {300, 399}}, "200, 3xx", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.yaml, func(t *testing.T) {
+			var page Page
+			assert.NoError(t, yaml.Unmarshal([]byte(tt.yaml), &page))
+			assert.Equal(t, tt.expected, page.Status)
+			assert.Equal(t, tt.str, page.Status.String())
+			assert.Equal(t, tt.redirect, page.Status.ExpectsRedirect())
+		})
+	}
+}
+
+func TestExpectedStatus_Invalid(t *testing.T) {
+	for _, value := range []string{"status: ok", `status: "9xx"`, `status: "299-200"`, "status: 42"} {
+		var page Page
+		assert.Error(t, yaml.Unmarshal([]byte(value), &page), value)
+	}
+}
+
+func TestExpectedStatus_Matches(t *testing.T) {
+	var status ExpectedStatus
+	assert.True(t, status.Matches(200))
+	assert.False(t, status.Matches(204))
+	assert.Equal(t, "200", status.String())
+
+	status = ExpectedStatus{{200, 299}}
+	assert.True(t, status.Matches(204))
+	assert.False(t, status.Matches(301))
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/marshallku/statusy/jsonpath"
+)
+
+// Page types
+const (
+	TypeHTTP  = "http"
+	TypeSteps = "steps"
+)
+
+// Step is one request of a multi-step page. It accepts the same options as a
+// page, with name naming the step, and can capture values from its response
+// into variables used by later steps as ${name}.
+type Step struct {
+	Page    `yaml:",inline"`
+	Capture []Capture `yaml:"capture,omitempty"`
+}
+
+// Capture extracts a value from a step's response into a variable. Exactly one
+// source must be set. Regex captures the first group, or the whole match when
+// the pattern has no groups.
+type Capture struct {
+	Name     string `yaml:"name"`
+	JSONPath string `yaml:"jsonpath,omitempty"`
+	Regex    string `yaml:"regex,omitempty"`
+	Header   string `yaml:"header,omitempty"`
+	Cookie   string `yaml:"cookie,omitempty"`
+}
+
+// ReadsBody reports whether the capture reads the response body.
+func (c Capture) ReadsBody() bool {
+	return c.JSONPath != "" || c.Regex != ""
+}
+
+func (c Capture) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("capture requires a name")
+	}
+
+	sources := 0
+	for _, source := range []string{c.JSONPath, c.Regex, c.Header, c.Cookie} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return fmt.Errorf("capture %s must set exactly one of jsonpath, regex, header or cookie", c.Name)
+	}
+
+	if c.JSONPath != "" {
+		if _, err := jsonpath.Compile(c.JSONPath); err != nil {
+			return err
+		}
+	}
+	if c.Regex != "" {
+		if _, err := regexp.Compile(c.Regex); err != nil {
+			return fmt.Errorf("invalid regex for capture %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func (p Page) validateSteps() error {
+	switch p.Type {
+	case "", TypeHTTP:
+		if len(p.Steps) > 0 {
+			return fmt.Errorf("steps require type: %s", TypeSteps)
+		}
+		return nil
+	case TypeSteps:
+		if len(p.Steps) == 0 {
+			return fmt.Errorf("type %s requires at least one step", TypeSteps)
+		}
+	default:
+		return fmt.Errorf("unknown type %q", p.Type)
+	}
+
+	for i, step := range p.Steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+		if step.Type != "" || len(step.Steps) > 0 {
+			return fmt.Errorf("step %s: steps cannot be nested", name)
+		}
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("step %s: %w", name, err)
+		}
+		for _, capture := range step.Capture {
+			if err := capture.validate(); err != nil {
+				return fmt.Errorf("step %s: %w", name, err)
+			}
+			if step.HeadOnly && capture.ReadsBody() {
+				return fmt.Errorf("step %s: head_only steps cannot capture from the response body", name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// TLS customizes how a page's server is verified and how statusy
+// authenticates itself to it.
+type TLS struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system ones
+	CAFile string `yaml:"ca_file,omitempty"`
+	// CertFile and KeyFile are the client certificate used for mutual TLS
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+	// ServerName overrides the name sent with SNI and used for verification
+	ServerName string `yaml:"server_name,omitempty"`
+	// MinVersion is one of 1.0, 1.1, 1.2 or 1.3
+	MinVersion string `yaml:"min_version,omitempty"`
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Version returns the minimum TLS version, or 0 for the Go default.
+func (t TLS) Version() (uint16, error) {
+	if t.MinVersion == "" {
+		return 0, nil
+	}
+	version, ok := tlsVersions[t.MinVersion]
+	if !ok {
+		return 0, fmt.Errorf("unknown TLS version %q", t.MinVersion)
+	}
+	return version, nil
+}
+
+func (t TLS) validate() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return fmt.Errorf("tls cert_file and key_file must be set together")
+	}
+	_, err := t.Version()
+	return err
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 2
+	DefaultIdleConnTimeout     = 90
+)
+
+// Transport tunes the connections shared by all checks.
+type Transport struct {
+	MaxIdleConns        int `yaml:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host,omitempty"`
+	// MaxConnsPerHost limits all connections to a host, 0 means no limit
+	MaxConnsPerHost int `yaml:"max_conns_per_host,omitempty"`
+	// IdleConnTimeout is how long an idle connection is kept in seconds
+	IdleConnTimeout int `yaml:"idle_conn_timeout,omitempty"`
+}
+
+// IdleConns returns the idle connection limits, applying the defaults.
+func (t Transport) IdleConns() (total, perHost int) {
+	total, perHost = t.MaxIdleConns, t.MaxIdleConnsPerHost
+	if total == 0 {
+		total = DefaultMaxIdleConns
+	}
+	if perHost == 0 {
+		perHost = DefaultMaxIdleConnsPerHost
+	}
+	return total, perHost
+}
+
+// IdleTimeout returns how long an idle connection is kept.
+func (t Transport) IdleTimeout() time.Duration {
+	if t.IdleConnTimeout == 0 {
+		return DefaultIdleConnTimeout * time.Second
+	}
+	return time.Duration(t.IdleConnTimeout) * time.Second
+}
+
+func (t Transport) validate() error {
+	if t.MaxIdleConns < 0 || t.MaxIdleConnsPerHost < 0 || t.MaxConnsPerHost < 0 || t.IdleConnTimeout < 0 {
+		return fmt.Errorf("connection limits must not be negative")
+	}
+	return nil
+}
+
+// KeepAliveEnabled reports whether connections to the page are reused
+// between checks. Disabling it measures a cold connection every time.
+func (p Page) KeepAliveEnabled() bool {
+	return p.KeepAlive == nil || *p.KeepAlive
+}
+
+// HTTP2Enabled reports whether HTTP/2 may be negotiated with the page.
+func (p Page) HTTP2Enabled() bool {
+	return p.HTTP2 == nil || *p.HTTP2
+}
@@ -6,6 +6,8 @@ require gopkg.in/yaml.v2 v2.4.0
 
 require github.com/gorilla/websocket v1.5.3
 
+require golang.org/x/crypto v0.31.0
+
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
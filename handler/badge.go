@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marshallku/statusy/templates"
+	"github.com/marshallku/statusy/types"
+)
+
+const (
+	badgeDefaultWindow = 30
+	badgeCharWidth     = 7
+	badgePadding       = 10
+
+	colorBrightGreen = "#4c1"
+	colorGreen       = "#97ca00"
+	colorYellow      = "#dfb317"
+	colorRed         = "#e05d44"
+	colorGrey        = "#9f9f9f"
+)
+
+type badge struct {
+	Label      string
+	Message    string
+	Color      string
+	LabelWidth int
+	Width      int
+}
+
+func newBadge(label, message, color string) badge {
+	labelWidth := textWidth(label)
+	return badge{
+		Label:      label,
+		Message:    message,
+		Color:      color,
+		LabelWidth: labelWidth,
+		Width:      labelWidth + textWidth(message),
+	}
+}
+
+func (b badge) LabelCenter() int {
+	return b.LabelWidth / 2
+}
+
+func (b badge) MessageCenter() int {
+	return b.LabelWidth + (b.Width-b.LabelWidth)/2
+}
+
+// textWidth approximates the rendered width of text in the badge font.
+func textWidth(text string) int {
+	return len([]rune(text))*badgeCharWidth + badgePadding
+}
+
+// HandleBadge serves /badge/{monitor}.svg, where monitor is a page ID. The
+// badge kind is chosen with ?type=status|uptime|response, and uptime and
+// response badges cover the last ?window days, e.g. 7d.
+func (s *Handler) HandleBadge(w http.ResponseWriter, r *http.Request) {
+	monitor, ok := strings.CutSuffix(r.PathValue("monitor"), ".svg")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	window, err := parseWindow(r.URL.Query().Get("window"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, ok := s.store.GetResults()[monitor]
+	checked := ok && result.Checked()
+	status := http.StatusOK
+	if !checked {
+		status = http.StatusNotFound
+	}
+
+	var b badge
+	switch kind := r.URL.Query().Get("type"); kind {
+	case "", "status":
+		b = statusBadge(result, checked)
+	case "uptime", "response":
+		days := s.store.GetDailyUptime(monitor, window, time.Now())
+		uptime, responseTime, ok := types.SummarizeUptime(days)
+		if kind == "uptime" {
+			b = uptimeBadge(uptime, ok)
+		} else {
+			b = responseBadge(responseTime, ok)
+		}
+	default:
+		http.Error(w, "type must be status, uptime or response", http.StatusBadRequest)
+		return
+	}
+
+	if label := r.URL.Query().Get("label"); label != "" {
+		b = newBadge(label, b.Message, b.Color)
+	}
+
+	var buf bytes.Buffer
+	if err := templates.BadgeTemplate.Execute(&buf, b); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha1.Sum(buf.Bytes())
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", s.badgeMaxAge()))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// badgeMaxAge lets caches keep a badge until the next check could change it.
+func (s *Handler) badgeMaxAge() int {
+	return int(s.cfg.Interval().Seconds())
+}
+
+func parseWindow(window string) (int, error) {
+	if window == "" {
+		return badgeDefaultWindow, nil
+	}
+
+	days, err := strconv.Atoi(strings.TrimSuffix(window, "d"))
+	if err != nil || days < 1 || days > 90 {
+		return 0, fmt.Errorf("window must be between 1d and 90d")
+	}
+	return days, nil
+}
+
+func statusBadge(result types.CheckResult, checked bool) badge {
+	switch {
+	case !checked:
+		return newBadge("status", "unknown", colorGrey)
+	case result.Status:
+		return newBadge("status", "up", colorBrightGreen)
+	default:
+		return newBadge("status", "down", colorRed)
+	}
+}
+
+func uptimeBadge(uptime float64, ok bool) badge {
+	if !ok {
+		return newBadge("uptime", "unknown", colorGrey)
+	}
+
+	color := colorRed
+	switch {
+	case uptime >= 99.9:
+		color = colorBrightGreen
+	case uptime >= 99:
+		color = colorGreen
+	case uptime >= 95:
+		color = colorYellow
+	}
+	return newBadge("uptime", fmt.Sprintf("%.2f%%", uptime), color)
+}
+
+func responseBadge(responseTime float64, ok bool) badge {
+	if !ok || responseTime == 0 {
+		return newBadge("response time", "unknown", colorGrey)
+	}
+
+	color := colorRed
+	switch {
+	case responseTime < 300:
+		color = colorBrightGreen
+	case responseTime < 1000:
+		color = colorGreen
+	case responseTime < 3000:
+		color = colorYellow
+	}
+	return newBadge("response time", fmt.Sprintf("%.0f ms", responseTime), color)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newBadgeHandler() *Handler {
+	s := store.NewStore()
+	now := time.Now()
+	for range 4 {
+		s.UpdateResult(types.CheckResult{ID: "api", Status: true, StatusCode: 200, ResponseTime: 120, LastChecked: now})
+	}
+	s.UpdateResult(types.CheckResult{ID: "web", Status: false, LastChecked: now})
+	return NewHandler(&config.Config{CheckInterval: 30}, s)
+}
+
+func serveBadge(s *Handler, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	s.Routes().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleBadge(t *testing.T) {
+	s := newBadgeHandler()
+
+	tests := []struct {
+		name     string
+		target   string
+		status   int
+		contains []string
+	}{
+		{name: "status up", target: "/badge/api.svg", status: http.StatusOK, contains: []string{"up", colorBrightGreen}},
+		{name: "status down", target: "/badge/web.svg?type=status", status: http.StatusOK, contains: []string{"down", colorRed}},
+		{name: "unknown monitor", target: "/badge/missing.svg", status: http.StatusNotFound, contains: []string{"unknown", colorGrey}},
+		{name: "missing suffix", target: "/badge/api", status: http.StatusNotFound},
+		{name: "uptime", target: "/badge/api.svg?type=uptime&window=7d", status: http.StatusOK, contains: []string{"100.00%", colorBrightGreen}},
+		{name: "uptime down", target: "/badge/web.svg?type=uptime", status: http.StatusOK, contains: []string{"0.00%", colorRed}},
+		{name: "response time", target: "/badge/api.svg?type=response", status: http.StatusOK, contains: []string{"120 ms", colorBrightGreen}},
+		{name: "response time without responses", target: "/badge/web.svg?type=response", status: http.StatusOK, contains: []string{"unknown", colorGrey}},
+		{name: "custom label", target: "/badge/api.svg?label=api", status: http.StatusOK, contains: []string{">api<"}},
+		{name: "unknown type", target: "/badge/api.svg?type=latency", status: http.StatusBadRequest},
+		{name: "shortest window", target: "/badge/api.svg?type=uptime&window=1d", status: http.StatusOK},
+		{name: "longest window", target: "/badge/api.svg?type=uptime&window=90d", status: http.StatusOK},
+		{name: "window too short", target: "/badge/api.svg?type=uptime&window=0d", status: http.StatusBadRequest},
+		{name: "window too long", target: "/badge/api.svg?type=uptime&window=91d", status: http.StatusBadRequest},
+		{name: "invalid window", target: "/badge/api.svg?type=uptime&window=week", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serveBadge(s, tt.target, nil)
+			assert.Equal(t, tt.status, rec.Code)
+			for _, expected := range tt.contains {
+				assert.Contains(t, rec.Body.String(), expected)
+			}
+		})
+	}
+}
+
+func TestHandleBadge_Caching(t *testing.T) {
+	s := newBadgeHandler()
+
+	rec := serveBadge(s, "/badge/api.svg", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=30", rec.Header().Get("Cache-Control"))
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	rec = serveBadge(s, "/badge/api.svg", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	// A different badge has a different ETag
+	rec = serveBadge(s, "/badge/web.svg", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestBadgeColors(t *testing.T) {
+	tests := []struct {
+		badge   badge
+		message string
+		color   string
+	}{
+		{badge: uptimeBadge(100, true), message: "100.00%", color: colorBrightGreen},
+		{badge: uptimeBadge(99.9, true), message: "99.90%", color: colorBrightGreen},
+		{badge: uptimeBadge(99.5, true), message: "99.50%", color: colorGreen},
+		{badge: uptimeBadge(99, true), message: "99.00%", color: colorGreen},
+		{badge: uptimeBadge(95, true), message: "95.00%", color: colorYellow},
+		{badge: uptimeBadge(94.99, true), message: "94.99%", color: colorRed},
+		{badge: uptimeBadge(0, false), message: "unknown", color: colorGrey},
+		{badge: responseBadge(299, true), message: "299 ms", color: colorBrightGreen},
+		{badge: responseBadge(300, true), message: "300 ms", color: colorGreen},
+		{badge: responseBadge(999, true), message: "999 ms", color: colorGreen},
+		{badge: responseBadge(1000, true), message: "1000 ms", color: colorYellow},
+		{badge: responseBadge(3000, true), message: "3000 ms", color: colorRed},
+		{badge: responseBadge(0, true), message: "unknown", color: colorGrey},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.message, tt.badge.Message)
+		assert.Equal(t, tt.color, tt.badge.Color, tt.message)
+	}
+}
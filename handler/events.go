@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/marshallku/statusy/store"
+)
+
+// eventKeepAlive is how often a comment is sent on an idle event stream so
+// that proxies don't time it out.
+const eventKeepAlive = 30 * time.Second
+
+// eventRetry is the reconnection delay suggested to clients, in milliseconds.
+const eventRetry = 1000
+
+// HandleEvents streams the same messages as the WebSocket as server-sent
+// events. Each event is named after the message type and carries the message
+// as JSON, with the sequence number as its ID so that clients resume with
+// Last-Event-ID after reconnecting.
+func (s *Handler) HandleEvents(w http.ResponseWriter, r *http.Request) {
+	sub := parseSubscription(r)
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		if since, err := strconv.ParseUint(lastEventID, 10, 64); err == nil {
+			sub.Since = since
+		}
+	}
+
+	controller := http.NewResponseController(w)
+	hub := s.store.Hub()
+	subscriber, initial := s.store.Subscribe(sub.Filter, sub.Since)
+	defer hub.Unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	flush := func() bool {
+		controller.SetWriteDeadline(time.Now().Add(writeWait))
+		return controller.Flush() == nil
+	}
+
+	fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+	for _, message := range initial {
+		if writeEvent(w, message) != nil {
+			return
+		}
+	}
+	if !flush() {
+		return
+	}
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message, ok := <-subscriber.Messages():
+			if !ok {
+				// Evicted for being too slow or shutting down, the client
+				// reconnects and resumes
+				return
+			}
+			if writeEvent(w, message) != nil || !flush() {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || !flush() {
+				return
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, message store.Message) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.Seq, message.Type, data)
+	return err
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	id   string
+	name string
+	data string
+}
+
+func (e event) seq(t *testing.T) uint64 {
+	seq, err := strconv.ParseUint(e.id, 10, 64)
+	assert.NoError(t, err)
+	return seq
+}
+
+// eventStream reads server-sent events from a response.
+type eventStream struct {
+	resp   *http.Response
+	reader *bufio.Reader
+}
+
+func openEventStream(t *testing.T, url string, lastEventID string) *eventStream {
+	req, err := http.NewRequest(http.MethodGet, url+"/api/v1/events", nil)
+	assert.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	return &eventStream{resp: resp, reader: bufio.NewReader(resp.Body)}
+}
+
+// next returns the next event, skipping the retry hint and comments.
+func (s *eventStream) next(t *testing.T) event {
+	var e event
+	for {
+		line, err := s.reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if e.name != "" {
+				return e
+			}
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func (s *eventStream) close() {
+	s.resp.Body.Close()
+}
+
+func TestHandleEvents(t *testing.T) {
+	st := store.NewStore()
+	s := NewHandler(&config.Config{}, st)
+	server := httptest.NewServer(s.Routes())
+	defer server.Close()
+
+	// New clients start from a snapshot
+	stream := openEventStream(t, server.URL, "")
+	snapshot := stream.next(t)
+	assert.Equal(t, store.MessageSnapshot, snapshot.name)
+
+	st.UpdateResult(types.CheckResult{ID: "api", Status: true, LastChecked: time.Now()})
+	assert.Equal(t, store.MessageResult, stream.next(t).name)
+	last := stream.next(t)
+	assert.Equal(t, store.MessageHistory, last.name)
+	stream.close()
+
+	// Reconnecting replays only the events missed in between
+	st.UpdateResult(types.CheckResult{ID: "api", Status: false, Reason: "timeout", LastChecked: time.Now()})
+	stream = openEventStream(t, server.URL, last.id)
+	var missed []string
+	for range 3 {
+		e := stream.next(t)
+		assert.Greater(t, e.seq(t), last.seq(t))
+		missed = append(missed, e.name)
+	}
+	assert.Equal(t, []string{store.MessageResult, store.MessageIncidentOpened, store.MessageHistory}, missed)
+	stream.close()
+
+	// Clients that can't resume get a snapshot instead
+	for _, lastEventID := range []string{"1", "invalid"} {
+		stream = openEventStream(t, server.URL, lastEventID)
+		e := stream.next(t)
+		assert.Equal(t, store.MessageSnapshot, e.name, lastEventID)
+		assert.Contains(t, e.data, `"timeout"`)
+		stream.close()
+	}
+}
+
+func TestHandleEvents_HubClosed(t *testing.T) {
+	st := store.NewStore()
+	s := NewHandler(&config.Config{}, st)
+	server := httptest.NewServer(s.Routes())
+	defer server.Close()
+
+	stream := openEventStream(t, server.URL, "")
+	defer stream.close()
+	assert.Equal(t, store.MessageSnapshot, stream.next(t).name)
+
+	// The stream ends when the hub closes, e.g. on shutdown
+	st.Hub().Close()
+	_, err := io.ReadAll(stream.reader)
+	assert.NoError(t, err)
+}
@@ -1,26 +1,76 @@
 package handler
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
 	"net/http"
+	"sync"
 
+	"github.com/gorilla/websocket"
+	"github.com/marshallku/statusy/auth"
+	"github.com/marshallku/statusy/config"
 	"github.com/marshallku/statusy/store"
 )
 
 type Handler struct {
-	store *store.Store
+	cfg      *config.Config
+	store    *store.Store
+	auth     *auth.Authenticator
+	upgrader websocket.Upgrader
+	server   *http.Server
+	// clients counts the open WebSocket connections, which the server no
+	// longer tracks once they are upgraded
+	clients sync.WaitGroup
 }
 
-func NewHandler(store *store.Store) *Handler {
-	return &Handler{store: store}
+func NewHandler(cfg *config.Config, store *store.Store) *Handler {
+	authenticator := auth.New(cfg)
+	handler := &Handler{
+		cfg:   cfg,
+		store: store,
+		auth:  authenticator,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     authenticator.CheckOrigin,
+		},
+	}
+	handler.server = &http.Server{Handler: handler.Routes()}
+	return handler
 }
 
-func (s *Handler) RegisterRoutes() error {
-	http.HandleFunc("/", s.HandleIndex)
-	http.HandleFunc("/history", s.HandleHistory)
-	http.HandleFunc("/ws", s.HandleWebSocket)
+// Shutdown disconnects WebSocket and event stream clients, then stops the
+// server gracefully, waiting for open requests until ctx is done.
+// ListenAndServe returns http.ErrServerClosed once Shutdown is called.
+func (s *Handler) Shutdown(ctx context.Context) error {
+	// Closing the hub ends every stream; WebSocket clients are told the
+	// server is going away so that they reconnect to another instance
+	s.store.Hub().Close()
 
-	fmt.Println("Server started on http://localhost:8080")
+	if err := s.server.Shutdown(ctx); err != nil {
+		return err
+	}
 
-	return http.ListenAndServe(":8080", nil)
+	// No connection can be upgraded anymore, so the count only goes down
+	closed := make(chan struct{})
+	go func() {
+		s.clients.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
 }
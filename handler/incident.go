@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/marshallku/statusy/auth"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/templates"
+	"github.com/marshallku/statusy/types"
+)
+
+type acknowledgeRequest struct {
+	By string `json:"by"`
+}
+
+type incidentUpdateRequest struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func (s *Handler) HandleIncidents(w http.ResponseWriter, r *http.Request) {
+	templates.IncidentsTemplate.Execute(w, nil)
+}
+
+// HandleListIncidents serves incidents as JSON, optionally filtered with
+// ?status=open or ?status=resolved.
+func (s *Handler) HandleListIncidents(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "open" && status != "resolved" {
+		writeError(w, http.StatusBadRequest, "status must be open or resolved")
+		return
+	}
+
+	incidents := make([]types.Incident, 0)
+	for _, incident := range s.store.GetIncidents() {
+		if status == "open" && !incident.IsOpen() || status == "resolved" && incident.IsOpen() {
+			continue
+		}
+		incidents = append(incidents, incident)
+	}
+
+	writeJSON(w, http.StatusOK, incidents)
+}
+
+func (s *Handler) HandleGetIncident(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid incident id")
+		return
+	}
+
+	incident, err := s.store.GetIncident(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, incident)
+}
+
+func (s *Handler) HandleAcknowledgeIncident(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid incident id")
+		return
+	}
+
+	var body acknowledgeRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	if identity, ok := auth.FromContext(r.Context()); ok && body.By == "" {
+		body.By = identity.Name
+	}
+
+	incident, err := s.store.AcknowledgeIncident(id, body.By)
+	switch {
+	case errors.Is(err, store.ErrIncidentNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, store.ErrIncidentResolved):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, incident)
+	}
+}
+
+func (s *Handler) HandleAddIncidentUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid incident id")
+		return
+	}
+
+	var body incidentUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if strings.TrimSpace(body.Message) == "" {
+		writeError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+
+	incident, err := s.store.AddIncidentUpdate(id, types.IncidentUpdate{
+		Status:  body.Status,
+		Message: body.Message,
+	})
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, incident)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/types"
+)
+
+type monitor struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Tags        []string           `json:"tags"`
+	URL         string             `json:"url"`
+	Result      *types.CheckResult `json:"result"`
+}
+
+func newMonitor(page config.Page, results map[string]types.CheckResult) monitor {
+	m := monitor{
+		ID:          page.ID,
+		Name:        page.Name,
+		Description: page.Description,
+		Tags:        page.Tags,
+		URL:         page.URL,
+	}
+	if m.Tags == nil {
+		m.Tags = []string{}
+	}
+	if result, ok := results[page.ID]; ok {
+		m.Result = &result
+	}
+	return m
+}
+
+// HandleListMonitors serves the configured pages with their latest result.
+// Use ?tag= to filter by tag and ?group=tag to group monitors by tag.
+func (s *Handler) HandleListMonitors(w http.ResponseWriter, r *http.Request) {
+	tag := r.URL.Query().Get("tag")
+	group := r.URL.Query().Get("group")
+	if group != "" && group != "tag" {
+		writeError(w, http.StatusBadRequest, "group must be tag")
+		return
+	}
+
+	results := s.store.GetResults()
+	monitors := make([]monitor, 0, len(s.cfg.Pages))
+	for _, page := range s.cfg.Pages {
+		if tag != "" && !page.HasTag(tag) {
+			continue
+		}
+		monitors = append(monitors, newMonitor(page, results))
+	}
+
+	if group == "" {
+		writeJSON(w, http.StatusOK, monitors)
+		return
+	}
+
+	groups := make(map[string][]monitor)
+	for _, m := range monitors {
+		if len(m.Tags) == 0 {
+			groups[""] = append(groups[""], m)
+		}
+		for _, t := range m.Tags {
+			if tag == "" || t == tag {
+				groups[t] = append(groups[t], m)
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+func (s *Handler) HandleGetMonitor(w http.ResponseWriter, r *http.Request) {
+	page, ok := s.cfg.FindPage(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "monitor not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newMonitor(page, s.store.GetResults()))
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/marshallku/statusy/config"
+)
+
+// Routes returns the handler serving the web interface and API under the
+// configured base path.
+func (s *Handler) Routes() http.Handler {
+	read := func(next http.HandlerFunc) http.HandlerFunc {
+		return s.auth.Require(config.RoleRead, next)
+	}
+	admin := func(next http.HandlerFunc) http.HandlerFunc {
+		return s.auth.Require(config.RoleAdmin, next)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", read(s.HandleIndex))
+	mux.HandleFunc("/history", read(s.HandleHistory))
+	mux.HandleFunc("/incidents", read(s.HandleIncidents))
+	mux.HandleFunc("/ws", read(s.HandleWebSocket))
+	mux.HandleFunc("GET /api/v1/monitors", read(s.HandleListMonitors))
+	mux.HandleFunc("GET /api/v1/monitors/{id}", read(s.HandleGetMonitor))
+	mux.HandleFunc("GET /api/v1/events", read(s.HandleEvents))
+	mux.HandleFunc("GET /api/v1/incidents", read(s.HandleListIncidents))
+	mux.HandleFunc("GET /api/v1/incidents/{id}", read(s.HandleGetIncident))
+	mux.HandleFunc("POST /api/v1/incidents/{id}/ack", admin(s.HandleAcknowledgeIncident))
+	mux.HandleFunc("POST /api/v1/incidents/{id}/updates", admin(s.HandleAddIncidentUpdate))
+
+	// The public status page and badges are meant to be embedded anywhere
+	mux.HandleFunc("/status", s.HandleStatusPage)
+	mux.HandleFunc("GET /badge/{monitor}", s.HandleBadge)
+
+	mux.HandleFunc("GET /auth/login", s.auth.HandleLogin)
+	mux.HandleFunc("GET /auth/callback", s.auth.HandleCallback)
+	mux.HandleFunc("GET /auth/logout", s.auth.HandleLogout)
+
+	prefix := s.cfg.Server.Prefix()
+	if prefix == "" {
+		return mux
+	}
+
+	stripped := http.StripPrefix(prefix, mux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The pages link relative to the base path, which only works with a
+		// trailing slash
+		if r.URL.Path == prefix {
+			http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, prefix+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		stripped.ServeHTTP(w, r)
+	})
+}
+
+// ListenAndServe serves the web interface and API on the configured address
+// or Unix socket, over HTTPS when a certificate is configured. It returns
+// http.ErrServerClosed after Shutdown.
+func (s *Handler) ListenAndServe() error {
+	server := s.cfg.Server
+	listener, err := listen(server.Socket, server.ListenAddress())
+	if err != nil {
+		return err
+	}
+
+	scheme := "http"
+	if server.TLS() {
+		reloader, err := newCertReloader(server.CertFile, server.KeyFile)
+		if err != nil {
+			listener.Close()
+			return err
+		}
+		listener = tls.NewListener(listener, &tls.Config{
+			GetCertificate: reloader.GetCertificate,
+			NextProtos:     []string{"h2", "http/1.1"},
+		})
+		scheme = "https"
+	}
+
+	if server.Socket != "" {
+		fmt.Printf("Server started on unix:%s\n", server.Socket)
+	} else {
+		fmt.Printf("Server started on %s://%s%s/\n", scheme, displayAddress(server.ListenAddress()), server.Prefix())
+	}
+
+	return s.server.Serve(listener)
+}
+
+func listen(socket, address string) (net.Listener, error) {
+	if socket == "" {
+		return net.Listen("tcp", address)
+	}
+
+	// A socket left behind by a previous run would make listening fail
+	if info, err := os.Stat(socket); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(socket); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", socket)
+}
+
+// displayAddress turns a listen address like ":8080" into one that can be
+// opened in a browser.
+func displayAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != "" {
+		return address
+	}
+	return net.JoinHostPort("localhost", port)
+}
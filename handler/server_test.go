@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdown(t *testing.T) {
+	// Unix socket paths are limited to about 100 bytes, too short for t.TempDir
+	dir, err := os.MkdirTemp("", "statusy")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "statusy.sock")
+
+	s := NewHandler(&config.Config{Server: config.Server{Socket: socket}}, store.NewStore())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ListenAndServe()
+	}()
+
+	dial := func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+	}
+	assert.Eventually(t, func() bool {
+		conn, err := dial(context.Background(), "", "")
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	ws, _, err := (&websocket.Dialer{NetDialContext: dial}).Dial("ws://statusy/ws", nil)
+	assert.NoError(t, err)
+	defer ws.Close()
+	_, _, err = ws.ReadMessage()
+	assert.NoError(t, err)
+
+	client := &http.Client{Transport: &http.Transport{DialContext: dial}}
+	resp, err := client.Get("http://statusy/api/v1/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+	line, err := events.ReadString('\n')
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "retry:"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx))
+	assert.ErrorIs(t, <-served, http.ErrServerClosed)
+
+	// WebSocket clients are told to reconnect elsewhere
+	for {
+		if _, _, err = ws.ReadMessage(); err != nil {
+			break
+		}
+	}
+	var closeErr *websocket.CloseError
+	assert.True(t, errors.As(err, &closeErr))
+	assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+
+	// The event stream ends after the events already sent
+	_, err = io.ReadAll(events)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/templates"
+	"github.com/marshallku/statusy/types"
+)
+
+// recentIncidentWindow is how far back resolved incidents are listed on the
+// public status page.
+const recentIncidentWindow = 7 * 24 * time.Hour
+
+type statusPageData struct {
+	Title      string
+	AllUp      bool
+	Components []componentStatus
+	Incidents  []incidentStatus
+	UpdatedAt  time.Time
+}
+
+type componentStatus struct {
+	Name     string
+	Status   string
+	Monitors []monitorStatus
+}
+
+type monitorStatus struct {
+	Name    string
+	Up      bool
+	Checked bool
+	Uptime  string
+	Days    []uptimeBar
+}
+
+type uptimeBar struct {
+	Class string
+	Label string
+}
+
+type incidentStatus struct {
+	Monitor    string
+	StartedAt  time.Time
+	ResolvedAt *time.Time
+	Updates    []types.IncidentUpdate
+}
+
+func (s *Handler) HandleStatusPage(w http.ResponseWriter, r *http.Request) {
+	templates.StatusPageTemplate.Execute(w, s.buildStatusPage(time.Now()))
+}
+
+func (s *Handler) buildStatusPage(now time.Time) statusPageData {
+	title := s.cfg.StatusPage.Title
+	if title == "" {
+		title = "Status"
+	}
+
+	data := statusPageData{
+		Title:     title,
+		AllUp:     true,
+		UpdatedAt: now,
+	}
+
+	results := s.store.GetResults()
+	shown := make(map[string]bool)
+
+	for _, component := range s.components() {
+		status := componentStatus{Name: component.Name}
+		down := 0
+
+		for _, id := range component.Pages {
+			page, ok := s.cfg.FindPage(id)
+			if !ok {
+				continue
+			}
+
+			result, ok := results[page.ID]
+			checked := ok && result.Checked()
+			monitor := monitorStatus{
+				Name:    page.Name,
+				Up:      checked && result.Status,
+				Checked: checked,
+			}
+			monitor.Uptime, monitor.Days = uptimeBars(s.store.GetDailyUptime(page.ID, store.UptimeDays, now))
+			if checked && !result.Status {
+				down++
+			}
+			status.Monitors = append(status.Monitors, monitor)
+			shown[page.ID] = true
+		}
+
+		switch {
+		case down == 0:
+			status.Status = "Operational"
+		case down == len(status.Monitors):
+			status.Status = "Major outage"
+		default:
+			status.Status = "Partial outage"
+		}
+		if down > 0 {
+			data.AllUp = false
+		}
+
+		data.Components = append(data.Components, status)
+	}
+
+	for _, incident := range s.store.GetIncidents() {
+		if !shown[incident.MonitorID] {
+			continue
+		}
+		if !incident.IsOpen() && now.Sub(*incident.ResolvedAt) > recentIncidentWindow {
+			continue
+		}
+
+		updates := make([]types.IncidentUpdate, 0, len(incident.Updates))
+		for i := len(incident.Updates) - 1; i >= 0; i-- {
+			updates = append(updates, incident.Updates[i])
+		}
+
+		data.Incidents = append(data.Incidents, incidentStatus{
+			Monitor:    incident.Name,
+			StartedAt:  incident.StartedAt,
+			ResolvedAt: incident.ResolvedAt,
+			Updates:    updates,
+		})
+	}
+
+	return data
+}
+
+// components returns the configured components, or a single component with
+// every page when none are configured.
+func (s *Handler) components() []config.Component {
+	if len(s.cfg.StatusPage.Components) > 0 {
+		return s.cfg.StatusPage.Components
+	}
+
+	pages := make([]string, 0, len(s.cfg.Pages))
+	for _, page := range s.cfg.Pages {
+		pages = append(pages, page.ID)
+	}
+	return []config.Component{{Name: "Services", Pages: pages}}
+}
+
+// uptimeBars summarizes daily uptime as an overall percentage and one bar per
+// day.
+func uptimeBars(days []types.UptimeDay) (string, []uptimeBar) {
+	bars := make([]uptimeBar, 0, len(days))
+
+	for _, day := range days {
+		uptime, ok := day.Uptime()
+		bar := uptimeBar{Class: "none", Label: day.Date + ": no data"}
+		if ok {
+			bar.Label = fmt.Sprintf("%s: %.2f%%", day.Date, uptime)
+			switch {
+			case uptime >= 99.9:
+				bar.Class = "up"
+			case uptime >= 95:
+				bar.Class = "degraded"
+			default:
+				bar.Class = "down"
+			}
+		}
+		bars = append(bars, bar)
+	}
+
+	uptime, _, ok := types.SummarizeUptime(days)
+	if !ok {
+		return "-", bars
+	}
+	return fmt.Sprintf("%.2f%%", uptime), bars
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildStatusPage(t *testing.T) {
+	cfg := &config.Config{
+		Pages: []config.Page{
+			{ID: "web", Name: "Web"},
+			{ID: "assets", Name: "Assets"},
+			{ID: "api", Name: "API"},
+			{ID: "docs", Name: "Docs"},
+			{ID: "new", Name: "New"},
+			{ID: "internal", Name: "Internal"},
+		},
+		StatusPage: config.StatusPage{
+			Components: []config.Component{
+				{Name: "Website", Pages: []string{"web", "assets"}},
+				{Name: "Backend", Pages: []string{"api"}},
+				{Name: "Documentation", Pages: []string{"docs", "new"}},
+			},
+		},
+	}
+
+	now := time.Now()
+	day := 24 * time.Hour
+	s := store.NewStore()
+	update := func(id string, status bool, at time.Time) {
+		s.UpdateResult(types.CheckResult{ID: id, Name: id, Status: status, LastChecked: at})
+	}
+
+	// Resolved more than a week ago
+	update("web", false, now.Add(-10*day))
+	update("web", true, now.Add(-9*day))
+	// Resolved within the last week, with updates
+	update("assets", false, now.Add(-3*day))
+	incident, _ := s.GetOpenIncident("assets")
+	s.AddIncidentUpdate(incident.ID, types.IncidentUpdate{Message: "Investigating", CreatedAt: now.Add(-3 * day)})
+	s.AddIncidentUpdate(incident.ID, types.IncidentUpdate{Message: "Fixed", CreatedAt: now.Add(-2 * day)})
+	update("assets", true, now.Add(-2*day))
+	// Still open
+	update("web", false, now.Add(-time.Hour))
+	update("api", false, now)
+	update("docs", true, now)
+	// Not part of any component
+	update("internal", false, now)
+
+	data := NewHandler(cfg, s).buildStatusPage(now)
+	assert.False(t, data.AllUp)
+
+	statuses := make(map[string]string)
+	for _, component := range data.Components {
+		statuses[component.Name] = component.Status
+	}
+	assert.Equal(t, map[string]string{
+		"Website":       "Partial outage",
+		"Backend":       "Major outage",
+		"Documentation": "Operational",
+	}, statuses)
+
+	// Pages that were never checked aren't counted as down
+	documentation := data.Components[2]
+	assert.Equal(t, "New", documentation.Monitors[1].Name)
+	assert.False(t, documentation.Monitors[1].Checked)
+	assert.False(t, documentation.Monitors[1].Up)
+
+	var incidents []string
+	for _, incident := range data.Incidents {
+		incidents = append(incidents, incident.Monitor)
+		if incident.Monitor == "assets" {
+			assert.NotNil(t, incident.ResolvedAt)
+			assert.Equal(t, "Fixed", incident.Updates[0].Message)
+			assert.Equal(t, "Investigating", incident.Updates[1].Message)
+		}
+	}
+	assert.ElementsMatch(t, []string{"web", "api", "assets"}, incidents)
+}
+
+func TestBuildStatusPage_AllUp(t *testing.T) {
+	cfg := &config.Config{
+		StatusPage: config.StatusPage{Title: "Example"},
+		Pages:      []config.Page{{ID: "web", Name: "Web"}, {ID: "api", Name: "API"}},
+	}
+	s := store.NewStore()
+	s.UpdateResult(types.CheckResult{ID: "web", Status: true, LastChecked: time.Now()})
+	s.UpdateResult(types.CheckResult{ID: "api", Status: true, LastChecked: time.Now()})
+
+	data := NewHandler(cfg, s).buildStatusPage(time.Now())
+	assert.Equal(t, "Example", data.Title)
+	assert.True(t, data.AllUp)
+	assert.Empty(t, data.Incidents)
+
+	// Without components, every page is listed under one
+	assert.Len(t, data.Components, 1)
+	assert.Equal(t, "Operational", data.Components[0].Status)
+	assert.Len(t, data.Components[0].Monitors, 2)
+}
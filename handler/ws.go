@@ -1,38 +1,176 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/marshallku/statusy/store"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin: func(r *http.Request) bool {
-		return true // Be more restrictive in production
-	},
+const (
+	// writeWait is how long a single write to a client may take
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent before it is dropped
+	pongWait = 60 * time.Second
+	// pingPeriod must be shorter than pongWait so that pongs arrive in time
+	pingPeriod = pongWait * 9 / 10
+	// maxMessageSize limits messages from clients
+	maxMessageSize = 4096
+)
+
+// subscription is what a client asks to receive: the messages matching
+// Filter, starting after the message numbered Since.
+type subscription struct {
+	store.Filter
+	Since uint64 `json:"since,omitempty"`
+}
+
+// clientMessage is a message sent by a WebSocket client.
+type clientMessage struct {
+	Type string `json:"type"`
+	subscription
+}
+
+// parseSubscription reads a subscription from query parameters. monitor, tag
+// and type may be repeated or hold comma separated lists.
+func parseSubscription(r *http.Request) subscription {
+	query := r.URL.Query()
+	list := func(key string) []string {
+		var values []string
+		for _, value := range query[key] {
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+		}
+		return values
+	}
+
+	since, _ := strconv.ParseUint(query.Get("since"), 10, 64)
+	return subscription{
+		Filter: store.Filter{
+			Monitors: list("monitor"),
+			Tags:     list("tag"),
+			Types:    list("type"),
+		},
+		Since: since,
+	}
 }
 
 func (s *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// Counted before upgrading, while the server still waits for the request
+	// on shutdown
+	s.clients.Add(1)
+	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
+		s.clients.Done()
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	s.store.AddClient(conn)
-	defer s.store.RemoveClient(conn)
+	subscriptions := make(chan subscription, 1)
+	subscriptions <- parseSubscription(r)
+	done := make(chan struct{})
+
+	go s.writePump(conn, subscriptions, done)
+
+	readPump(conn, subscriptions, done)
+}
+
+// readPump reads from a client until it disconnects or stops answering
+// pings, passing subscribe messages on to writePump. Reading is also what
+// processes the client's pongs.
+func readPump(conn *websocket.Conn, subscriptions chan subscription, done chan<- struct{}) {
+	defer close(done)
 
-	conn.WriteJSON(store.Message{
-		Type: "results",
-		Data: s.store.GetResults(),
+	conn.SetReadLimit(maxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
 	})
 
 	for {
-		if _, _, err := conn.ReadMessage(); err != nil {
-			break
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var message clientMessage
+		if json.Unmarshal(data, &message) != nil || message.Type != "subscribe" {
+			continue
+		}
+		// Only the latest subscription matters
+		select {
+		case <-subscriptions:
+		default:
+		}
+		subscriptions <- message.subscription
+	}
+}
+
+// writePump is the only goroutine writing to a client. For every
+// subscription, it sends what the client needs to catch up, then the
+// matching updates, and it pings the client periodically. It closes the
+// connection when the client is evicted for being too slow, the server shuts
+// down, a write fails or readPump is done.
+func (s *Handler) writePump(conn *websocket.Conn, subscriptions <-chan subscription, done <-chan struct{}) {
+	hub := s.store.Hub()
+	ticker := time.NewTicker(pingPeriod)
+	var subscriber *store.Subscriber
+	var messages <-chan store.Message
+
+	defer func() {
+		ticker.Stop()
+		if subscriber != nil {
+			hub.Unsubscribe(subscriber)
+		}
+		conn.Close()
+		s.clients.Done()
+	}()
+
+	write := func(message interface{}) bool {
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		return conn.WriteJSON(message) == nil
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case sub := <-subscriptions:
+			if subscriber != nil {
+				hub.Unsubscribe(subscriber)
+			}
+			var initial []store.Message
+			subscriber, initial = s.store.Subscribe(sub.Filter, sub.Since)
+			messages = subscriber.Messages()
+			for _, message := range initial {
+				if !write(message) {
+					return
+				}
+			}
+		case message, ok := <-messages:
+			if !ok {
+				closeMessage := websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow")
+				if hub.Closed() {
+					closeMessage = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+				}
+				conn.SetWriteDeadline(time.Now().Add(writeWait))
+				conn.WriteMessage(websocket.CloseMessage, closeMessage)
+				return
+			}
+			if !write(message) {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
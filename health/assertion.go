@@ -0,0 +1,128 @@
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/jsonpath"
+)
+
+// checkAssertions evaluates the JSONPath assertions of a page against the
+// response body and returns a description of the first one that fails.
+func checkAssertions(assertions []config.Assertion, body []byte) error {
+	if len(assertions) == 0 {
+		return nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return fmt.Errorf("response body is not valid JSON: %v", err)
+	}
+
+	for _, assertion := range assertions {
+		if err := checkAssertion(assertion, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAssertion passes when every value selected by the path satisfies the
+// operator.
+func checkAssertion(assertion config.Assertion, data interface{}) error {
+	path, err := jsonpath.Compile(assertion.Path)
+	if err != nil {
+		return err
+	}
+
+	values := path.Find(data)
+
+	switch assertion.Operator {
+	case config.OpExists:
+		if len(values) == 0 {
+			return fmt.Errorf("%s does not exist", assertion.Path)
+		}
+		return nil
+	case config.OpNotExists:
+		if len(values) > 0 {
+			return fmt.Errorf("%s exists", assertion.Path)
+		}
+		return nil
+	}
+
+	if len(values) == 0 {
+		return fmt.Errorf("%s does not exist", assertion.Path)
+	}
+
+	for _, value := range values {
+		ok, err := compare(assertion.Operator, value, assertion.Value)
+		if err != nil {
+			return fmt.Errorf("%s %s: %v", assertion.Path, assertion.Operator, err)
+		}
+		if !ok {
+			return fmt.Errorf("expected %s %s %v, got %s", assertion.Path, assertion.Operator, assertion.Value, printable(formatValue(value)))
+		}
+	}
+	return nil
+}
+
+func compare(operator string, actual interface{}, expected interface{}) (bool, error) {
+	switch operator {
+	case config.OpEquals:
+		return equal(actual, expected), nil
+	case config.OpNotEquals:
+		return !equal(actual, expected), nil
+	case config.OpContains:
+		if array, ok := actual.([]interface{}); ok {
+			for _, item := range array {
+				if equal(item, expected) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return strings.Contains(fmt.Sprint(actual), fmt.Sprint(expected)), nil
+	case config.OpGreaterThan, config.OpLessThan:
+		a, ok := config.ToFloat(actual)
+		if !ok {
+			return false, fmt.Errorf("%s is not a number", formatValue(actual))
+		}
+		b, _ := config.ToFloat(expected)
+		if operator == config.OpGreaterThan {
+			return a > b, nil
+		}
+		return a < b, nil
+	case config.OpRegex:
+		re, err := regexp.Compile(fmt.Sprint(expected))
+		if err != nil {
+			return false, err
+		}
+		return re.MatchString(fmt.Sprint(actual)), nil
+	}
+	return false, fmt.Errorf("unknown operator %q", operator)
+}
+
+// equal compares a JSON value with a YAML value, treating numbers of any type
+// as equal when their values are.
+func equal(actual interface{}, expected interface{}) bool {
+	a, aIsNumber := config.ToFloat(actual)
+	b, bIsNumber := config.ToFloat(expected)
+	if aIsNumber && bIsNumber {
+		return a == b
+	}
+	if actual == nil || expected == nil {
+		return actual == expected
+	}
+	return fmt.Sprint(actual) == fmt.Sprint(expected)
+}
+
+func formatValue(value interface{}) string {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(encoded)
+}
@@ -0,0 +1,64 @@
+package health
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAssertions(t *testing.T) {
+	body := []byte(`{"status":"ok","db":"degraded","latency":42,"regions":["eu","us"],"items":[{"ok":true},{"ok":true}]}`)
+
+	tests := []struct {
+		name      string
+		assertion config.Assertion
+		pass      bool
+	}{
+		{"equals", config.Assertion{Path: "$.status", Operator: config.OpEquals, Value: "ok"}, true},
+		{"equals fails", config.Assertion{Path: "$.db", Operator: config.OpEquals, Value: "ok"}, false},
+		{"equals number", config.Assertion{Path: "$.latency", Operator: config.OpEquals, Value: 42}, true},
+		{"not equals", config.Assertion{Path: "$.db", Operator: config.OpNotEquals, Value: "down"}, true},
+		{"contains string", config.Assertion{Path: "$.db", Operator: config.OpContains, Value: "grad"}, true},
+		{"contains array", config.Assertion{Path: "$.regions", Operator: config.OpContains, Value: "us"}, true},
+		{"contains array fails", config.Assertion{Path: "$.regions", Operator: config.OpContains, Value: "ap"}, false},
+		{"greater than", config.Assertion{Path: "$.latency", Operator: config.OpGreaterThan, Value: 10}, true},
+		{"less than fails", config.Assertion{Path: "$.latency", Operator: config.OpLessThan, Value: 10.5}, false},
+		{"less than on string", config.Assertion{Path: "$.db", Operator: config.OpLessThan, Value: 10}, false},
+		{"exists", config.Assertion{Path: "$.status", Operator: config.OpExists}, true},
+		{"exists fails", config.Assertion{Path: "$.missing", Operator: config.OpExists}, false},
+		{"not exists", config.Assertion{Path: "$.missing", Operator: config.OpNotExists}, true},
+		{"regex", config.Assertion{Path: "$.status", Operator: config.OpRegex, Value: "^o[a-z]$"}, true},
+		{"missing path", config.Assertion{Path: "$.missing", Operator: config.OpEquals, Value: "ok"}, false},
+		{"every match", config.Assertion{Path: "$.items[*].ok", Operator: config.OpEquals, Value: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAssertions([]config.Assertion{tt.assertion}, body)
+			if tt.pass {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckAssertions_InvalidJSON(t *testing.T) {
+	err := checkAssertions([]config.Assertion{{Path: "$.status", Operator: config.OpExists}}, []byte("<html>"))
+	assert.Error(t, err)
+
+	assert.NoError(t, checkAssertions(nil, []byte("<html>")))
+}
+
+func TestCheckAssertions_LongValue(t *testing.T) {
+	// The actual value can be a whole object, which is cut short in the
+	// reason
+	body := []byte(`{"data":{"message":"` + strings.Repeat("a", 500) + `"}}`)
+	err := checkAssertions([]config.Assertion{{Path: "$.data", Operator: config.OpEquals, Value: "ok"}}, body)
+	assert.Error(t, err)
+	assert.Less(t, len(err.Error()), 200)
+	assert.True(t, strings.HasSuffix(err.Error(), "..."))
+}
@@ -0,0 +1,200 @@
+package health
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+)
+
+// tokenRefreshMargin is how long before expiry a cached token is replaced.
+const tokenRefreshMargin = 30 * time.Second
+
+// defaultTokenLifetime is used when the token endpoint doesn't say when the
+// token expires.
+const defaultTokenLifetime = 5 * time.Minute
+
+type cachedToken struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+// tokenCache keeps OAuth2 access tokens between checks. Only one request
+// per token is in flight at a time; checks needing the same token wait for it
+// while other tokens are fetched independently.
+type tokenCache struct {
+	mu       sync.Mutex
+	tokens   map[string]cachedToken
+	fetching map[string]*tokenFetch
+	now      func() time.Time
+}
+
+// tokenFetch is a token request in flight. done is closed once token and err
+// are set.
+type tokenFetch struct {
+	done  chan struct{}
+	token cachedToken
+	err   error
+}
+
+var tokens = newTokenCache(time.Now)
+
+func newTokenCache(now func() time.Time) *tokenCache {
+	return &tokenCache{
+		tokens:   make(map[string]cachedToken),
+		fetching: make(map[string]*tokenFetch),
+		now:      now,
+	}
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// tokenKey identifies the token of a set of client credentials. The secret
+// is hashed so that it isn't kept around in plain text.
+func tokenKey(auth *config.Auth) string {
+	secret := sha256.Sum256([]byte(auth.ClientSecret))
+	return auth.TokenURL + "|" + auth.ClientID + "|" + hex.EncodeToString(secret[:]) + "|" + strings.Join(auth.Scopes, " ")
+}
+
+// applyAuth adds the credentials described by auth to the request checking
+// page.
+func applyAuth(ctx context.Context, cfg *config.Config, page config.Page, auth *config.Auth, req *http.Request) error {
+	if auth == nil {
+		return nil
+	}
+
+	switch auth.Type {
+	case config.AuthBasic:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case config.AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case config.AuthOAuth2:
+		token, err := tokens.get(ctx, cfg, page, auth)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	default:
+		return fmt.Errorf("unknown auth type %q", auth.Type)
+	}
+	return nil
+}
+
+// invalidateAuth drops the cached token of auth, e.g. after the server
+// rejected it.
+func invalidateAuth(auth *config.Auth) {
+	if auth == nil || auth.Type != config.AuthOAuth2 {
+		return
+	}
+
+	tokens.mu.Lock()
+	defer tokens.mu.Unlock()
+	delete(tokens.tokens, tokenKey(auth))
+}
+
+// get returns a valid token for auth, fetching one if none is cached. The
+// cache isn't locked during the request.
+func (c *tokenCache) get(ctx context.Context, cfg *config.Config, page config.Page, auth *config.Auth) (string, error) {
+	key := tokenKey(auth)
+
+	c.mu.Lock()
+	if token, ok := c.tokens[key]; ok && c.now().Add(tokenRefreshMargin).Before(token.expiresAt) {
+		c.mu.Unlock()
+		return token.accessToken, nil
+	}
+	fetch, inFlight := c.fetching[key]
+	if !inFlight {
+		fetch = &tokenFetch{done: make(chan struct{})}
+		c.fetching[key] = fetch
+	}
+	c.mu.Unlock()
+
+	if !inFlight {
+		fetch.token, fetch.err = c.fetch(ctx, cfg, page, auth)
+
+		c.mu.Lock()
+		if fetch.err == nil {
+			c.tokens[key] = fetch.token
+		}
+		delete(c.fetching, key)
+		c.mu.Unlock()
+		close(fetch.done)
+	}
+
+	select {
+	case <-fetch.done:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	if fetch.err != nil {
+		return "", fetch.err
+	}
+	return fetch.token.accessToken, nil
+}
+
+// fetch requests a token with the client credentials grant. The token
+// endpoint is reached through the page's network options, such as its proxy,
+// but usually lives on another host, so it is verified with the auth's own TLS
+// options rather than the page's.
+func (c *tokenCache) fetch(ctx context.Context, cfg *config.Config, page config.Page, auth *config.Auth) (cachedToken, error) {
+	transport, err := transports.get(cfg, config.Page{Network: page.Network, TLS: auth.TLS})
+	if err != nil {
+		return cachedToken{}, err
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(auth.Scopes) > 0 {
+		form.Set("scope", strings.Join(auth.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", auth.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return cachedToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(auth.ClientID), url.QueryEscape(auth.ClientSecret))
+
+	client := &http.Client{Transport: transport, Timeout: time.Duration(cfg.Timeout) * time.Millisecond}
+	resp, err := client.Do(req)
+	if err != nil {
+		return cachedToken{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return cachedToken{}, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return cachedToken{}, fmt.Errorf("invalid token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return cachedToken{}, fmt.Errorf("token response has no access_token")
+	}
+
+	lifetime := defaultTokenLifetime
+	if token.ExpiresIn > 0 {
+		lifetime = time.Duration(token.ExpiresIn) * time.Second
+	}
+
+	return cachedToken{
+		accessToken: token.AccessToken,
+		expiresAt:   c.now().Add(lifetime),
+	}, nil
+}
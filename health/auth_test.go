@@ -0,0 +1,294 @@
+package health
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTokenServer(requests *int32, expiresIn int) *httptest.Server {
+	return httptest.NewServer(tokenHandler(requests, expiresIn))
+}
+
+func tokenHandler(requests *int32, expiresIn int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		r.ParseForm()
+		if !ok || id != "client" || secret != "secret" || r.Form.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := atomic.AddInt32(requests, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "Bearer",
+			"expires_in":   expiresIn,
+		})
+	})
+}
+
+func newProtectedServer(authorization *atomic.Value) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func resetTokens(now func() time.Time) {
+	tokens = newTokenCache(now)
+}
+
+func TestCheckPage_StaticAuth(t *testing.T) {
+	var authorization atomic.Value
+	server := newProtectedServer(&authorization)
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+
+	result := checkPage(context.Background(), cfg, config.Page{
+		URL:     server.URL,
+		Request: &config.Request{Auth: &config.Auth{Type: config.AuthBasic, Username: "jane", Password: "pw"}},
+	})
+	assert.True(t, result.Status)
+	assert.Equal(t, "Basic amFuZTpwdw==", authorization.Load())
+
+	result = checkPage(context.Background(), cfg, config.Page{
+		URL:     server.URL,
+		Request: &config.Request{Auth: &config.Auth{Type: config.AuthBearer, Token: "static"}},
+	})
+	assert.True(t, result.Status)
+	assert.Equal(t, "Bearer static", authorization.Load())
+}
+
+func TestCheckPage_OAuth2ClientCredentials(t *testing.T) {
+	now := time.Now()
+	resetTokens(func() time.Time { return now })
+
+	var tokenRequests int32
+	tokenServer := newTokenServer(&tokenRequests, 3600)
+	defer tokenServer.Close()
+
+	var authorization atomic.Value
+	server := newProtectedServer(&authorization)
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+	page := config.Page{
+		URL: server.URL,
+		Request: &config.Request{Auth: &config.Auth{
+			Type:         config.AuthOAuth2,
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "secret",
+			Scopes:       []string{"health:read"},
+		}},
+	}
+
+	assert.True(t, checkPage(context.Background(), cfg, page).Status)
+	assert.True(t, checkPage(context.Background(), cfg, page).Status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenRequests), "token should be cached")
+	assert.Equal(t, "Bearer token-1", authorization.Load())
+
+	// Refresh shortly before the token expires
+	now = now.Add(3600*time.Second - tokenRefreshMargin + time.Second)
+	assert.True(t, checkPage(context.Background(), cfg, page).Status)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tokenRequests))
+	assert.Equal(t, "Bearer token-2", authorization.Load())
+}
+
+func TestCheckPage_OAuth2Failure(t *testing.T) {
+	resetTokens(time.Now)
+
+	var tokenRequests int32
+	tokenServer := newTokenServer(&tokenRequests, 3600)
+	defer tokenServer.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+	page := config.Page{
+		URL: "http://127.0.0.1:1",
+		Request: &config.Request{Auth: &config.Auth{
+			Type:         config.AuthOAuth2,
+			TokenURL:     tokenServer.URL,
+			ClientID:     "client",
+			ClientSecret: "wrong",
+		}},
+	}
+
+	result := checkPage(context.Background(), cfg, page)
+	assert.False(t, result.Status)
+	assert.Contains(t, result.Reason, "authenticate")
+}
+
+// writeServerCertificate writes a self-signed server certificate for name to
+// dir and returns it with the path of its PEM file.
+func writeServerCertificate(t *testing.T, dir string, name string) (tls.Certificate, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, certFile
+}
+
+func TestCheckPage_OAuth2Transport(t *testing.T) {
+	dir := t.TempDir()
+
+	// The page is verified as page.example, a name the token server's
+	// certificate doesn't have
+	var authorization atomic.Value
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization.Store(r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	certificate, pageCA := writeServerCertificate(t, dir, "page.example")
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	server.StartTLS()
+	defer server.Close()
+
+	var tokenRequests int32
+	tokenServer := httptest.NewTLSServer(tokenHandler(&tokenRequests, 3600))
+	defer tokenServer.Close()
+	tokenCA := filepath.Join(dir, "token.pem")
+	assert.NoError(t, os.WriteFile(tokenCA, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tokenServer.Certificate().Raw}), 0600))
+
+	tests := []struct {
+		name           string
+		tls            *config.TLS
+		expectedStatus bool
+	}{
+		{
+			name:           "token endpoint with its own TLS options",
+			tls:            &config.TLS{CAFile: tokenCA},
+			expectedStatus: true,
+		},
+		{
+			// Not even the page's CA is trusted for the token endpoint
+			name:           "token endpoint with default TLS options",
+			tls:            nil,
+			expectedStatus: false,
+		},
+	}
+
+	cfg := &config.Config{Timeout: 5000}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetTokens(time.Now)
+			page := config.Page{
+				URL: server.URL,
+				TLS: &config.TLS{CAFile: pageCA, ServerName: "page.example"},
+				Request: &config.Request{Auth: &config.Auth{
+					Type:         config.AuthOAuth2,
+					TokenURL:     tokenServer.URL,
+					ClientID:     "client",
+					ClientSecret: "secret",
+					TLS:          tt.tls,
+				}},
+			}
+
+			result := checkPage(context.Background(), cfg, page)
+			assert.Equal(t, tt.expectedStatus, result.Status, result.Reason)
+			if tt.expectedStatus {
+				assert.Equal(t, "Bearer token-1", authorization.Load())
+			} else {
+				assert.Contains(t, result.Reason, "authenticate")
+			}
+		})
+	}
+}
+
+func TestTokenCache_Concurrency(t *testing.T) {
+	cache := newTokenCache(time.Now)
+	cfg := &config.Config{Timeout: 5000}
+
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	var slowRequests int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&slowRequests, 1) == 1 {
+			close(arrived)
+		}
+		<-release
+		tokenHandler(new(int32), 3600).ServeHTTP(w, r)
+	}))
+	defer slow.Close()
+
+	var fastRequests int32
+	fast := newTokenServer(&fastRequests, 3600)
+	defer fast.Close()
+
+	slowAuth := &config.Auth{Type: config.AuthOAuth2, TokenURL: slow.URL, ClientID: "client", ClientSecret: "secret"}
+	fastAuth := &config.Auth{Type: config.AuthOAuth2, TokenURL: fast.URL, ClientID: "client", ClientSecret: "secret"}
+
+	// Checks needing the same token share one request
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := cache.get(context.Background(), cfg, config.Page{}, slowAuth)
+			assert.NoError(t, err)
+			assert.Equal(t, "token-1", token)
+		}()
+	}
+	<-arrived
+
+	// A slow token endpoint doesn't hold up other tokens
+	done := make(chan struct{})
+	go func() {
+		token, err := cache.get(context.Background(), cfg, config.Page{}, fastAuth)
+		assert.NoError(t, err)
+		assert.Equal(t, "token-1", token)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("token request was blocked by another token's request")
+	}
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slowRequests))
+}
+
+func TestTokenKey(t *testing.T) {
+	auth := config.Auth{TokenURL: "https://auth.example.com/token", ClientID: "client", ClientSecret: "secret"}
+	rotated := auth
+	rotated.ClientSecret = "rotated"
+
+	assert.NotEqual(t, tokenKey(&auth), tokenKey(&rotated))
+	assert.NotContains(t, tokenKey(&auth), "secret")
+}
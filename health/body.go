@@ -0,0 +1,120 @@
+package health
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/marshallku/statusy/config"
+)
+
+// checkBody evaluates the body assertions of a page and describes the first
+// one that fails.
+func checkBody(page config.Page, body []byte) error {
+	size := int64(len(body))
+
+	if page.MinBodySize > 0 && size < page.MinBodySize {
+		return fmt.Errorf("body is %d bytes, expected at least %d", size, page.MinBodySize)
+	}
+
+	if page.MaxBodySize > 0 && size > page.MaxBodySize {
+		return fmt.Errorf("body is %d bytes, expected at most %d", size, page.MaxBodySize)
+	}
+
+	for _, text := range page.TextToExclude {
+		if strings.Contains(string(body), text) {
+			return fmt.Errorf("string `%s` found in HTTP response", text)
+		}
+	}
+
+	if page.BodyRegex != "" {
+		re, err := regexp.Compile(page.BodyRegex)
+		if err != nil {
+			return err
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("body does not match `%s`", page.BodyRegex)
+		}
+	}
+
+	if page.BodyHash != "" {
+		sum := sha256.Sum256(body)
+		if actual := hex.EncodeToString(sum[:]); actual != page.ExpectedBodyHash() {
+			return fmt.Errorf("body hash is sha256:%s, expected sha256:%s", actual, page.ExpectedBodyHash())
+		}
+	}
+
+	return nil
+}
+
+const (
+	bodyChunkSize = 32 << 10
+	// maxDrainBytes is how much of a body that is no longer needed is still
+	// read, so that small responses don't cost the connection.
+	maxDrainBytes = 64 << 10
+)
+
+// readBody reads up to limit bytes of a response body. Unless full is set or
+// the page has checks that need the whole body, text_to_include and
+// body_regex are evaluated as the body arrives, and reading stops as soon as
+// both are satisfied. The body is one byte longer than limit when the
+// response was truncated.
+func readBody(page config.Page, r io.Reader, limit int64, full bool) (body []byte, truncated bool) {
+	reader := io.LimitReader(r, limit+1)
+
+	var re *regexp.Regexp
+	if page.BodyRegex != "" {
+		var err error
+		if re, err = regexp.Compile(page.BodyRegex); err != nil {
+			// Let checkBody report the invalid expression
+			full = true
+		}
+	}
+
+	if full || page.NeedsFullBody() {
+		body, _ = io.ReadAll(reader)
+		return body, int64(len(body)) > limit
+	}
+
+	text := []byte(page.TextToInclude)
+	included := len(text) == 0
+	matched := re == nil
+	// The regex is evaluated whenever the body has doubled in size, which
+	// keeps the total work linear in the size of the body
+	nextMatch := bodyChunkSize
+
+	chunk := make([]byte, bodyChunkSize)
+	for !included || !matched {
+		n, err := reader.Read(chunk)
+		if n > 0 {
+			start := max(len(body)-len(text)+1, 0)
+			body = append(body, chunk[:n]...)
+
+			if !included {
+				included = bytes.Contains(body[start:], text)
+			}
+			if !matched && len(body) >= nextMatch {
+				nextMatch = 2 * len(body)
+				// A match that ends before the last rune read doesn't depend on
+				// what follows, so it holds for the whole body
+				if loc := re.FindIndex(body); loc != nil && loc[1] <= len(body)-utf8.UTFMax {
+					matched = true
+				}
+			}
+		}
+		if err != nil {
+			return body, int64(len(body)) > limit
+		}
+	}
+
+	if int64(len(body)) > limit {
+		return body, true
+	}
+	io.CopyN(io.Discard, r, maxDrainBytes)
+	return body, false
+}
@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckBody(t *testing.T) {
+	body := []byte("<html>Welcome, version 1.2.3</html>")
+	hash := "sha256:6f90b08b2dc834f31e6191a266829f26059ca6e5dee346afe0994145a98bc30d"
+	otherHash := "6d1f09a7c0eb0b0dfeb2f1dcbeec84bb7b2b40e6f1ba23b02cbd2d4ad5b2d1c5"
+
+	tests := []struct {
+		name string
+		page config.Page
+		pass bool
+	}{
+		{"no assertions", config.Page{}, true},
+		{"text excluded", config.Page{TextToExclude: config.StringList{"maintenance", "Internal Server Error"}}, true},
+		{"text found", config.Page{TextToExclude: config.StringList{"maintenance", "Welcome"}}, false},
+		{"regex matches", config.Page{BodyRegex: `version \d+\.\d+\.\d+`}, true},
+		{"regex does not match", config.Page{BodyRegex: `^\{`}, false},
+		{"within size", config.Page{MinBodySize: 10, MaxBodySize: 100}, true},
+		{"too small", config.Page{MinBodySize: 100}, false},
+		{"too large", config.Page{MaxBodySize: 10}, false},
+		{"hash matches", config.Page{BodyHash: hash}, true},
+		{"hash mismatch", config.Page{BodyHash: otherHash}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBody(tt.page, body)
+			if tt.pass {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	const size = 1 << 20
+	body := "status: ok\n" + strings.Repeat("x", size) + "\nend"
+
+	tests := []struct {
+		name      string
+		page      config.Page
+		limit     int64
+		full      bool
+		read      int
+		truncated bool
+	}{
+		{name: "no body checks", page: config.Page{}, limit: 2 * size, read: 0},
+		{name: "text found early", page: config.Page{TextToInclude: "status: ok"}, limit: 2 * size, read: bodyChunkSize},
+		{name: "text at the end", page: config.Page{TextToInclude: "end"}, limit: 2 * size, read: len(body)},
+		{name: "regex matches early", page: config.Page{BodyRegex: `status: \w+`}, limit: 2 * size, read: bodyChunkSize},
+		{name: "regex anchored at the end", page: config.Page{BodyRegex: `end$`}, limit: 2 * size, read: len(body)},
+		{name: "full body required", page: config.Page{TextToInclude: "status: ok", MaxBodySize: 2 * size}, limit: 2 * size, read: len(body)},
+		{name: "full body requested", page: config.Page{TextToInclude: "status: ok"}, limit: 2 * size, full: true, read: len(body)},
+		{name: "truncated", page: config.Page{TextToInclude: "end"}, limit: 1000, read: 1001, truncated: true},
+		{name: "truncated full body", page: config.Page{BodyHash: strings.Repeat("0", 64)}, limit: 1000, read: 1001, truncated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			read, truncated := readBody(tt.page, strings.NewReader(body), tt.limit, tt.full)
+			assert.Len(t, read, tt.read)
+			assert.Equal(t, tt.truncated, truncated)
+		})
+	}
+}
+
+func TestCheckPage_BodyLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 4096) + "done"))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000, MaxBodyBytes: 1024}
+
+	result := checkPage(context.Background(), cfg, config.Page{URL: server.URL, TextToInclude: "done"})
+	assert.False(t, result.Status)
+	assert.True(t, result.BodyTruncated)
+	assert.Equal(t, int64(1024), result.BodySize)
+	assert.Contains(t, result.Reason, "only the first 1024 bytes were read")
+
+	result = checkPage(context.Background(), cfg, config.Page{URL: server.URL, TextToInclude: "done", MaxBodyBytes: 8192})
+	assert.True(t, result.Status, result.Reason)
+	assert.False(t, result.BodyTruncated)
+	assert.Equal(t, int64(4100), result.BodySize)
+}
+
+func TestCheckPage_HeadOnly(t *testing.T) {
+	var method string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+
+	result := checkPage(context.Background(), cfg, config.Page{URL: server.URL, HeadOnly: true})
+	assert.True(t, result.Status)
+	assert.Equal(t, http.MethodHead, method)
+
+	result = checkPage(context.Background(), cfg, config.Page{URL: server.URL, HeadOnly: true, Request: &config.Request{Headers: map[string]string{"Accept": "text/html"}}})
+	assert.True(t, result.Status)
+	assert.Equal(t, http.MethodHead, method)
+}
@@ -0,0 +1,109 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+)
+
+// newClient builds the HTTP client used to check a page. Clients are cheap,
+// the transport underneath is shared through the transport pool.
+func newClient(cfg *config.Config, page config.Page, redirects *redirectTracker, jar http.CookieJar) (*http.Client, error) {
+	transport, err := transports.get(cfg, page)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport:     transport,
+		Timeout:       time.Duration(cfg.Timeout) * time.Millisecond,
+		CheckRedirect: redirects.checkRedirect,
+		Jar:           jar,
+	}, nil
+}
+
+// configureNetwork applies the proxy, IP family, source address and pinned
+// addresses of network to transport.
+func configureNetwork(transport *http.Transport, network config.Network) error {
+	if network.IsZero() {
+		return nil
+	}
+
+	proxy, err := network.ProxyURL()
+	if err != nil {
+		return err
+	}
+	switch {
+	case proxy != nil:
+		transport.Proxy = http.ProxyURL(proxy)
+	case len(network.Resolve) > 0:
+		// A proxy from the environment would resolve the host itself
+		transport.Proxy = nil
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if network.SourceAddress != "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(network.SourceAddress)}
+	}
+
+	transport.DialContext = func(ctx context.Context, _, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if pinned, ok := network.Resolve[host]; ok {
+			address = net.JoinHostPort(pinned, port)
+		}
+		return dialer.DialContext(ctx, network.Network(), address)
+	}
+
+	return nil
+}
+
+func newTLSConfig(options *config.TLS) (*tls.Config, error) {
+	version, err := options.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         version,
+		ServerName:         options.ServerName,
+		InsecureSkipVerify: options.InsecureSkipVerify,
+	}
+
+	if options.CAFile != "" {
+		pem, err := os.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if options.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
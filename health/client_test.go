@@ -0,0 +1,201 @@
+package health
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeClientCertificate writes a self-signed client certificate and key to
+// dir and returns their paths.
+func writeClientCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "statusy"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestCheckPage_TLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+	certFile, keyFile := writeClientCertificate(t, dir)
+
+	cfg := &config.Config{Timeout: 5000}
+
+	tests := []struct {
+		name           string
+		tls            *config.TLS
+		expectedStatus bool
+		statusCode     int
+	}{
+		{
+			name:           "untrusted certificate",
+			tls:            nil,
+			expectedStatus: false,
+		},
+		{
+			name:           "insecure skip verify",
+			tls:            &config.TLS{InsecureSkipVerify: true},
+			expectedStatus: false,
+			statusCode:     http.StatusUnauthorized,
+		},
+		{
+			name:           "custom CA without client certificate",
+			tls:            &config.TLS{CAFile: caFile},
+			expectedStatus: false,
+			statusCode:     http.StatusUnauthorized,
+		},
+		{
+			name:           "mutual TLS",
+			tls:            &config.TLS{CAFile: caFile, CertFile: certFile, KeyFile: keyFile},
+			expectedStatus: true,
+			statusCode:     http.StatusOK,
+		},
+		{
+			name:           "server name mismatch",
+			tls:            &config.TLS{CAFile: caFile, ServerName: "statusy.invalid"},
+			expectedStatus: false,
+		},
+		{
+			name:           "missing CA file",
+			tls:            &config.TLS{CAFile: filepath.Join(dir, "missing.pem")},
+			expectedStatus: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkPage(context.Background(), cfg, config.Page{URL: server.URL, TLS: tt.tls})
+			assert.Equal(t, tt.expectedStatus, result.Status)
+			assert.Equal(t, tt.statusCode, result.StatusCode)
+		})
+	}
+}
+
+func TestNewTLSConfig_MinVersion(t *testing.T) {
+	tlsConfig, err := newTLSConfig(&config.TLS{MinVersion: "1.3"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+
+	_, err = newTLSConfig(&config.TLS{MinVersion: "2.0"})
+	assert.Error(t, err)
+}
+
+func TestCheckPage_Network(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer server.Close()
+
+	// The proxy answers every request itself, reporting the requested URL
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied " + r.URL.String()))
+	}))
+	defer proxy.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	pinnedURL := "http://statusy.test:" + port + "/"
+
+	tests := []struct {
+		name           string
+		global         config.Network
+		page           config.Page
+		expectedStatus bool
+	}{
+		{
+			name:           "pinned host",
+			page:           config.Page{URL: pinnedURL, TextToInclude: "statusy.test", Network: &config.Network{Resolve: map[string]string{"statusy.test": "127.0.0.1"}}},
+			expectedStatus: true,
+		},
+		{
+			name:           "pinned host with IPv6 forced",
+			page:           config.Page{URL: pinnedURL, Network: &config.Network{IPVersion: 6, Resolve: map[string]string{"statusy.test": "127.0.0.1"}}},
+			expectedStatus: false,
+		},
+		{
+			name:           "source address",
+			page:           config.Page{URL: server.URL, Network: &config.Network{IPVersion: 4, SourceAddress: "127.0.0.1"}},
+			expectedStatus: true,
+		},
+		{
+			name:           "global proxy",
+			global:         config.Network{Proxy: proxy.URL},
+			page:           config.Page{URL: "http://statusy.test/health", TextToInclude: "proxied http://statusy.test/health"},
+			expectedStatus: true,
+		},
+		{
+			name:           "page proxy",
+			page:           config.Page{URL: "http://statusy.test/health", TextToInclude: "proxied", Network: &config.Network{Proxy: proxy.URL}},
+			expectedStatus: true,
+		},
+		{
+			name:           "unsupported proxy scheme",
+			page:           config.Page{URL: server.URL, Network: &config.Network{Proxy: "ftp://127.0.0.1"}},
+			expectedStatus: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Timeout: 5000, Network: tt.global}
+			result := checkPage(context.Background(), cfg, tt.page)
+			assert.Equal(t, tt.expectedStatus, result.Status, result.Reason)
+		})
+	}
+}
+
+func TestConfigureNetwork_ResolveWithoutProxy(t *testing.T) {
+	// Pinned addresses are connected to directly, even with a proxy set in
+	// the environment
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	assert.NoError(t, configureNetwork(transport, config.Network{Resolve: map[string]string{"example.com": "127.0.0.1"}}))
+	assert.Nil(t, transport.Proxy)
+
+	transport = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	assert.NoError(t, configureNetwork(transport, config.Network{IPVersion: 4}))
+	assert.NotNil(t, transport.Proxy)
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/utils"
+)
+
+// escalate notifies every escalation step whose delay has elapsed since the
+// page's incident started, as long as nobody has acknowledged it yet.
+func escalate(ctx context.Context, cfg *config.Config, store *store.Store, page config.Page, now time.Time) {
+	steps := cfg.EscalationPolicy(page)
+	if len(steps) == 0 {
+		return
+	}
+
+	incident, ok := store.GetOpenIncident(page.ID)
+	if !ok || incident.Acknowledged {
+		return
+	}
+
+	downFor := now.Sub(incident.StartedAt)
+	level := incident.EscalationLevel
+
+	for level < len(steps) && downFor >= steps[level].Delay() {
+		err := utils.SendNotificationToWebhook(ctx, steps[level].WebhookURL, utils.NotificationParams{
+			Title:       "Unacknowledged incident",
+			Description: fmt.Sprintf("🚨 Page has been down for %s without acknowledgement", downFor.Round(time.Second)),
+			Color:       colorFailure,
+			Fields: map[string]string{
+				"Name":     page.Name,
+				"URL":      page.URL,
+				"Incident": fmt.Sprintf("#%d", incident.ID),
+				"Level":    fmt.Sprintf("%d/%d", level+1, len(steps)),
+			},
+		})
+		if err != nil {
+			fmt.Printf("Failed to escalate incident #%d: %v\n", incident.ID, err)
+		}
+		store.RecordNotification(page.ID, fmt.Sprintf("escalation level %d", level+1), err)
+		level++
+	}
+
+	if level != incident.EscalationLevel {
+		store.SetEscalationLevel(incident.ID, level)
+	}
+}
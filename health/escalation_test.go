@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newWebhookServer(calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func TestEscalate(t *testing.T) {
+	var secondCalls, thirdCalls int32
+	second := newWebhookServer(&secondCalls)
+	defer second.Close()
+	third := newWebhookServer(&thirdCalls)
+	defer third.Close()
+
+	cfg := &config.Config{
+		Escalation: []config.EscalationStep{
+			{After: 5, WebhookURL: second.URL},
+			{After: 15, WebhookURL: third.URL},
+		},
+	}
+	page := config.Page{ID: "example", URL: "https://example.com"}
+	startedAt := time.Now()
+
+	s := store.NewStore()
+	s.UpdateResult(types.CheckResult{ID: page.ID, URL: page.URL, Status: false, LastChecked: startedAt})
+
+	escalate(context.Background(), cfg, s, page, startedAt.Add(time.Minute))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&secondCalls))
+
+	escalate(context.Background(), cfg, s, page, startedAt.Add(6*time.Minute))
+	escalate(context.Background(), cfg, s, page, startedAt.Add(7*time.Minute))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&secondCalls))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&thirdCalls))
+
+	incident, ok := s.GetOpenIncident(page.ID)
+	assert.True(t, ok)
+	assert.Equal(t, 1, incident.EscalationLevel)
+
+	_, err := s.AcknowledgeIncident(incident.ID, "operator")
+	assert.NoError(t, err)
+
+	escalate(context.Background(), cfg, s, page, startedAt.Add(20*time.Minute))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&thirdCalls))
+}
+
+func TestEscalate_PageOverridesGlobalPolicy(t *testing.T) {
+	var globalCalls, pageCalls int32
+	global := newWebhookServer(&globalCalls)
+	defer global.Close()
+	pageHook := newWebhookServer(&pageCalls)
+	defer pageHook.Close()
+
+	cfg := &config.Config{
+		Escalation: []config.EscalationStep{{After: 0, WebhookURL: global.URL}},
+	}
+	page := config.Page{
+		ID:         "example",
+		URL:        "https://example.com",
+		Escalation: []config.EscalationStep{{After: 0, WebhookURL: pageHook.URL}},
+	}
+	startedAt := time.Now()
+
+	s := store.NewStore()
+	s.UpdateResult(types.CheckResult{ID: page.ID, URL: page.URL, Status: false, LastChecked: startedAt})
+
+	escalate(context.Background(), cfg, s, page, startedAt)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&globalCalls))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&pageCalls))
+}
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/marshallku/statusy/config"
+)
+
+// checkHeaders evaluates the header assertions of a page and describes the
+// first one that fails.
+func checkHeaders(assertions []config.HeaderAssertion, header http.Header) error {
+	for _, assertion := range assertions {
+		values, present := header[http.CanonicalHeaderKey(assertion.Name)]
+		value := header.Get(assertion.Name)
+
+		switch {
+		case assertion.Absent:
+			if present {
+				return fmt.Errorf("header %s should be absent, got %q", assertion.Name, printable(value))
+			}
+			continue
+		case !present || len(values) == 0:
+			return fmt.Errorf("header %s is missing", assertion.Name)
+		}
+
+		if assertion.Equals != "" && value != assertion.Equals {
+			return fmt.Errorf("header %s is %q, expected %q", assertion.Name, printable(value), assertion.Equals)
+		}
+
+		if assertion.Regex != "" {
+			re, err := regexp.Compile(assertion.Regex)
+			if err != nil {
+				return err
+			}
+			if !re.MatchString(value) {
+				return fmt.Errorf("header %s is %q, expected to match `%s`", assertion.Name, printable(value), assertion.Regex)
+			}
+		}
+	}
+	return nil
+}
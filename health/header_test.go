@@ -0,0 +1,78 @@
+package health
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Cache-Control", "public, max-age=300")
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Powered-By", "PHP")
+
+	tests := []struct {
+		name      string
+		assertion config.HeaderAssertion
+		pass      bool
+	}{
+		{"present", config.HeaderAssertion{Name: "cache-control"}, true},
+		{"missing", config.HeaderAssertion{Name: "ETag"}, false},
+		{"equals", config.HeaderAssertion{Name: "Content-Type", Equals: "application/json"}, true},
+		{"not equal", config.HeaderAssertion{Name: "Content-Type", Equals: "text/html"}, false},
+		{"regex", config.HeaderAssertion{Name: "Cache-Control", Regex: `max-age=\d+`}, true},
+		{"regex does not match", config.HeaderAssertion{Name: "Cache-Control", Regex: `no-store`}, false},
+		{"absent", config.HeaderAssertion{Name: "Server", Absent: true}, true},
+		{"should be absent", config.HeaderAssertion{Name: "X-Powered-By", Absent: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkHeaders([]config.HeaderAssertion{tt.assertion}, header)
+			if tt.pass {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestCheckHeaders_SecurityPreset(t *testing.T) {
+	page := config.Page{SecurityHeaders: true}
+
+	header := http.Header{}
+	header.Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+	header.Set("Content-Security-Policy", "default-src 'self'")
+	header.Set("X-Frame-Options", "DENY")
+	assert.NoError(t, checkHeaders(page.HeaderAssertions(), header))
+
+	header.Set("X-Frame-Options", "ALLOW-FROM https://example.com")
+	assert.Error(t, checkHeaders(page.HeaderAssertions(), header))
+
+	header.Set("X-Frame-Options", "SAMEORIGIN")
+	header.Del("Content-Security-Policy")
+	assert.Error(t, checkHeaders(page.HeaderAssertions(), header))
+}
+
+func TestCheckHeaders_ServerValue(t *testing.T) {
+	header := http.Header{}
+	header.Set("X-Frame-Options", "<img src=x onerror=alert(1)>\x1b[31m"+strings.Repeat("A", 200))
+
+	err := checkHeaders([]config.HeaderAssertion{{Name: "X-Frame-Options", Equals: "DENY"}}, header)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "\x1b")
+	assert.Less(t, len(err.Error()), 200)
+}
+
+func TestPrintable(t *testing.T) {
+	assert.Equal(t, "DENY", printable("DENY"))
+	assert.Equal(t, "tabsandnewlines", printable("tabs\tand\nnewlines"))
+	assert.Equal(t, "caf", printable("café"))
+	assert.Equal(t, strings.Repeat("A", maxReasonValueLength), printable(strings.Repeat("A", maxReasonValueLength)))
+	assert.Equal(t, strings.Repeat("A", maxReasonValueLength)+"...", printable(strings.Repeat("A", maxReasonValueLength+1)))
+}
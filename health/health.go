@@ -1,8 +1,9 @@
 package health
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 	"sync"
@@ -21,168 +22,327 @@ const (
 	MicrosecondsInSecond       = 1000000
 )
 
-func Check(cfg *config.Config, store *store.Store) {
-	var wg sync.WaitGroup
-	for _, page := range cfg.Pages {
-		wg.Add(1)
-		go func(p config.Page) {
-			defer wg.Done()
-			result := checkPage(cfg, p)
-			if store != nil {
-				store.UpdateResult(result)
+const (
+	colorFailure = "16007990"
+	colorWarning = "16761095"
+)
+
+// notificationChannel is the name incidents use for the global webhook.
+const notificationChannel = "webhook"
+
+// Check checks every page and records the results.
+func Check(ctx context.Context, cfg *config.Config, store *store.Store) {
+	c := newChecker(cfg, store)
+	c.start(ctx, cfg.Pages, func(config.Page) time.Duration { return cfg.Interval() }, nil)
+	c.wait()
+}
+
+// checker runs checks in the background within the concurrency limits and
+// records the results.
+type checker struct {
+	cfg     *config.Config
+	store   *store.Store
+	limiter *limiter
+	running sync.WaitGroup
+}
+
+func newChecker(cfg *config.Config, store *store.Store) *checker {
+	return &checker{cfg: cfg, store: store, limiter: newLimiter(cfg.Concurrency)}
+}
+
+// start checks pages in the background and passes each result to report if
+// it is set, with ok false when the check was dropped. Only as many checks as
+// the concurrency limits allow run at once; the others are marked pending
+// until a slot frees up. A check that hasn't finished by its deadline fails,
+// so a hung page can't hold up its next check; the deadline follows the
+// interval the page is checked at, which interval returns. When ctx is
+// cancelled, e.g. on shutdown, the running checks stop and their results are
+// dropped rather than reported as failures.
+func (c *checker) start(ctx context.Context, pages []config.Page, interval func(config.Page) time.Duration, report func(page config.Page, result types.CheckResult, ok bool)) {
+	// Slots are handed out before any check starts, so that the pages left
+	// waiting are marked pending together
+	acquired := make([]bool, len(pages))
+	var pending []types.CheckResult
+	for i, page := range pages {
+		acquired[i] = c.limiter.tryAcquire(pageHost(page))
+		if !acquired[i] {
+			pending = append(pending, newResult(page))
+		}
+	}
+	if c.store != nil {
+		c.store.MarkPending(pending...)
+	}
+
+	for i, page := range pages {
+		c.running.Add(1)
+		go func(p config.Page, acquired bool) {
+			defer c.running.Done()
+			result, ok := c.check(ctx, p, interval(p), acquired)
+			if report != nil {
+				report(p, result, ok)
 			}
-		}(page)
+		}(page, acquired[i])
+	}
+}
+
+// wait returns once every check started is done.
+func (c *checker) wait() {
+	c.running.Wait()
+}
+
+// check checks page and records the result, reporting whether it did. Unless
+// acquired is set, it waits for a check slot first.
+func (c *checker) check(ctx context.Context, page config.Page, interval time.Duration, acquired bool) (types.CheckResult, bool) {
+	checkCtx, cancel := context.WithTimeout(ctx, c.cfg.CheckDeadline(interval))
+	defer cancel()
+
+	host := pageHost(page)
+	if !acquired {
+		if err := c.limiter.acquire(checkCtx, host); err != nil {
+			if ctx.Err() == nil {
+				fmt.Printf("Skipped %s: no free check slot before the deadline\n", page.URL)
+			}
+			return types.CheckResult{}, false
+		}
+	}
+	result := checkPage(checkCtx, c.cfg, page)
+	c.limiter.release(host)
+	if ctx.Err() != nil {
+		return types.CheckResult{}, false
+	}
+
+	if c.store != nil {
+		c.store.UpdateResult(result)
+	}
+	// Notifications aren't bound to the check deadline, which may have
+	// passed for the very check they report
+	if result.Reason != "" {
+		err := notify(ctx, c.cfg, result)
+		if err != nil {
+			fmt.Printf("Failed to send notification for %s: %v\n", page.URL, err)
+		}
+		if c.store != nil && !errors.Is(err, utils.ErrWebhookNotSet) {
+			c.store.RecordNotification(page.ID, notificationChannel, err)
+		}
+	}
+	if c.store != nil {
+		escalate(ctx, c.cfg, c.store, page, time.Now())
+	}
+	return result, true
+}
+
+// notify sends the notification describing a failed or degraded check.
+func notify(ctx context.Context, cfg *config.Config, result types.CheckResult) error {
+	color := colorFailure
+	if result.Status {
+		color = colorWarning
+	}
+
+	fields := map[string]string{
+		"Name": result.Name,
+		"URL":  result.URL,
+	}
+	if result.StatusCode != 0 {
+		fields["Status Code"] = fmt.Sprintf("%d", result.StatusCode)
+		fields["Time Taken"] = result.TimeTaken
+	}
+
+	return utils.SendNotification(ctx, cfg, utils.NotificationParams{
+		Description: result.Reason,
+		Color:       color,
+		Fields:      fields,
+	})
+}
+
+// newResult returns the result of a page that hasn't been checked yet.
+func newResult(page config.Page) types.CheckResult {
+	return types.CheckResult{
+		ID:          page.ID,
+		Name:        page.Name,
+		Description: page.Description,
+		Tags:        page.Tags,
+		URL:         page.URL,
+		TimeTaken:   "0",
+	}
+}
+
+func checkPage(ctx context.Context, cfg *config.Config, page config.Page) types.CheckResult {
+	result := newResult(page)
+	result.LastChecked = time.Now()
+
+	if page.Type == config.TypeSteps {
+		checkSteps(ctx, cfg, page, &result)
+		return result
 	}
-	wg.Wait()
+
+	checkRequest(ctx, cfg, page, nil, false, &result)
+	return result
 }
 
-func checkPage(cfg *config.Config, page config.Page) types.CheckResult {
-	client := &http.Client{
-		Timeout: time.Duration(cfg.Timeout) * time.Millisecond,
+// checkRequest sends the request described by page and evaluates the
+// response into result. The response and its body are returned so that steps
+// can capture values from them; the response is nil if the request failed.
+// fullBody makes sure the body is read up to the limit even when the page's
+// own checks are satisfied earlier.
+func checkRequest(ctx context.Context, cfg *config.Config, page config.Page, jar http.CookieJar, fullBody bool, result *types.CheckResult) (*http.Response, []byte) {
+	redirects := &redirectTracker{page: page}
+	client, err := newClient(cfg, page, redirects, jar)
+	if err != nil {
+		result.Reason = fmt.Sprintf("🔧 Invalid client configuration: %v", err)
+		return nil, nil
 	}
 
 	var req *http.Request
-	var err error
-	checkedAt := time.Now()
 
 	if page.Request != nil {
-		req, err = http.NewRequest(page.Request.Method, page.URL, strings.NewReader(page.Request.Body))
+		method := page.Request.Method
+		if page.HeadOnly {
+			method = http.MethodHead
+		}
+		req, err = http.NewRequestWithContext(ctx, method, page.URL, strings.NewReader(page.Request.Body))
 		if err != nil {
-			utils.SendNotification(cfg, utils.NotificationParams{
-				Description: "🚫 Failed to create request",
-				Color:       "16007990",
-				Fields: map[string]string{
-					"URL": page.URL,
-				},
-			})
-			return types.CheckResult{
-				URL:         page.URL,
-				StatusCode:  0,
-				TimeTaken:   "0",
-				Status:      false,
-				LastChecked: checkedAt,
-			}
+			result.Reason = "🚫 Failed to create request"
+			return nil, nil
 		}
 		for key, value := range page.Request.Headers {
 			req.Header.Set(key, value)
 		}
+		if err := applyAuth(ctx, cfg, page, page.Request.Auth, req); err != nil {
+			result.Reason = fmt.Sprintf("🔑 Failed to authenticate: %v", err)
+			return nil, nil
+		}
 	} else {
-		req, err = http.NewRequest("GET", page.URL, nil)
+		method := http.MethodGet
+		if page.HeadOnly {
+			method = http.MethodHead
+		}
+		req, err = http.NewRequestWithContext(ctx, method, page.URL, nil)
 		if err != nil {
-			utils.SendNotification(cfg, utils.NotificationParams{
-				Description: "🚫 Failed to create request",
-				Color:       "16007990",
-				Fields: map[string]string{
-					"URL": page.URL,
-				},
-			})
-			return types.CheckResult{
-				URL:         page.URL,
-				StatusCode:  0,
-				TimeTaken:   "0",
-				Status:      false,
-				LastChecked: checkedAt,
-			}
+			result.Reason = "🚫 Failed to create request"
+			return nil, nil
 		}
 	}
 
 	start := time.Now()
 	resp, err := client.Do(req)
 	if err != nil {
-		utils.SendNotification(cfg, utils.NotificationParams{
-			Description: "🚫 Failed to connect to server",
-			Color:       "16007990",
-			Fields: map[string]string{
-				"URL": page.URL,
-			},
-		})
-		return types.CheckResult{
-			URL:         page.URL,
-			StatusCode:  0,
-			TimeTaken:   "0",
-			Status:      false,
-			LastChecked: checkedAt,
+		result.Reason = "🚫 Failed to connect to server"
+		if ctx.Err() != nil {
+			result.Reason = interruptedReason(ctx)
 		}
+		return nil, nil
 	}
 	defer resp.Body.Close()
 
 	duration := time.Since(start)
-	body, _ := io.ReadAll(resp.Body)
-	timeTakenInMicroseconds := duration.Microseconds()
-	timeTaken := fmt.Sprintf("%.3f ms", float64(duration.Microseconds())/MicrosecondsInMilliSeconds)
+	limit := cfg.BodyLimit(page)
+	body, truncated := readBody(page, resp.Body, limit, fullBody)
+	if ctx.Err() != nil {
+		// The body was cut short, so the checks below would be misleading
+		setTimeTaken(result, time.Since(start))
+		result.StatusCode = resp.StatusCode
+		result.Reason = interruptedReason(ctx)
+		return nil, nil
+	}
+	result.BodySize = min(int64(len(body)), limit)
+	result.BodyTruncated = truncated
 
-	if timeTakenInMicroseconds > MicrosecondsInSecond {
-		timeTaken = fmt.Sprintf("%.3f s", float64(timeTakenInMicroseconds)/MicrosecondsInSecond)
+	if resp.StatusCode == http.StatusUnauthorized && page.Request != nil {
+		// Fetch a new token next time in case the cached one was revoked
+		invalidateAuth(page.Request.Auth)
 	}
+	setTimeTaken(result, duration)
+	result.StatusCode = resp.StatusCode
+	result.Redirects = redirects.chain
+	result.FinalURL = resp.Request.URL.String()
 
-	if page.Speed > 0 && duration.Milliseconds() > int64(page.Speed) {
-		utils.SendNotification(cfg, utils.NotificationParams{
-			Description: "🐌 Server responded successfully, but it was too slow.",
-			Color:       "16761095",
-			Fields: map[string]string{
-				"URL":         page.URL,
-				"Status Code": fmt.Sprintf("%d", resp.StatusCode),
-				"Time Taken":  timeTaken,
-			},
-		})
-		return types.CheckResult{
-			URL:         page.URL,
-			StatusCode:  resp.StatusCode,
-			TimeTaken:   timeTaken,
-			Status:      true,
-			LastChecked: checkedAt,
-		}
+	if err := redirects.checkFinalURL(resp); err != nil {
+		result.Reason = fmt.Sprintf("🔀 Redirect check failed: %v", err)
+		return resp, body
 	}
 
-	expectedStatus := page.Status
-	if expectedStatus == 0 {
-		expectedStatus = 200
-	}
-
-	if expectedStatus != resp.StatusCode {
-		utils.SendNotification(cfg, utils.NotificationParams{
-			Description: fmt.Sprintf("🙅 Expected status is %d, but actual status is %d", page.Status, resp.StatusCode),
-			Color:       "16007990",
-			Fields: map[string]string{
-				"URL":         page.URL,
-				"Status Code": fmt.Sprintf("%d", resp.StatusCode),
-				"Time Taken":  timeTaken,
-			},
-		})
-		return types.CheckResult{
-			URL:         page.URL,
-			StatusCode:  resp.StatusCode,
-			TimeTaken:   timeTaken,
-			Status:      false,
-			LastChecked: checkedAt,
-		}
+	if !page.Status.Matches(resp.StatusCode) {
+		result.Reason = fmt.Sprintf("🙅 Expected status is %s, but actual status is %d", page.Status, resp.StatusCode)
+		return resp, body
 	}
 
 	if page.TextToInclude != "" && !strings.Contains(string(body), page.TextToInclude) {
-		utils.SendNotification(cfg, utils.NotificationParams{
-			Description: fmt.Sprintf("😑 String `%s` not found in HTTP response", page.TextToInclude),
-			Color:       "16007990",
-			Fields: map[string]string{
-				"URL":         page.URL,
-				"Status Code": fmt.Sprintf("%d", resp.StatusCode),
-				"Time Taken":  timeTaken,
-			},
-		})
-		return types.CheckResult{
-			URL:         page.URL,
-			StatusCode:  resp.StatusCode,
-			TimeTaken:   timeTaken,
-			Status:      false,
-			LastChecked: checkedAt,
-		}
+		result.Reason = fmt.Sprintf("😑 String `%s` not found in HTTP response%s", page.TextToInclude, truncationNote(truncated, limit))
+		return resp, body
 	}
 
+	if err := checkHeaders(page.HeaderAssertions(), resp.Header); err != nil {
+		result.Reason = fmt.Sprintf("📋 Header assertion failed: %v", err)
+		return resp, body
+	}
+
+	if err := checkBody(page, body); err != nil {
+		result.Reason = fmt.Sprintf("😑 Body assertion failed: %v%s", err, truncationNote(truncated, limit))
+		return resp, body
+	}
+
+	if err := checkAssertions(page.Assertions, body); err != nil {
+		result.Reason = fmt.Sprintf("🔍 Assertion failed: %v%s", err, truncationNote(truncated, limit))
+		return resp, body
+	}
+
+	result.Status = true
+	checkSpeed(page, duration, result)
+
 	fmt.Printf("Succeeded: %s with status %d\n", page.URL, resp.StatusCode)
-	return types.CheckResult{
-		URL:         page.URL,
-		StatusCode:  resp.StatusCode,
-		TimeTaken:   timeTaken,
-		Status:      true,
-		LastChecked: checkedAt,
+	return resp, body
+}
+
+// maxReasonValueLength bounds values from responses quoted in reasons.
+const maxReasonValueLength = 100
+
+// printable prepares a value received from a server to be quoted in a
+// reason. Only printable ASCII is kept and long values are truncated, so a
+// response can't flood notifications or smuggle control characters into them.
+func printable(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r < ' ' || r > '~' {
+			continue
+		}
+		if b.Len() == maxReasonValueLength {
+			return b.String() + "..."
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// interruptedReason explains a check stopped by its context.
+func interruptedReason(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "⏱️ Check did not finish before its deadline"
+	}
+	return "🛑 Check was cancelled"
+}
+
+// truncationNote explains a failed body check on a truncated body.
+func truncationNote(truncated bool, limit int64) string {
+	if !truncated {
+		return ""
+	}
+	return fmt.Sprintf(" (only the first %d bytes were read)", limit)
+}
+
+// checkSpeed warns about a successful check that took longer than the page
+// allows.
+func checkSpeed(page config.Page, duration time.Duration, result *types.CheckResult) {
+	if page.Speed > 0 && duration.Milliseconds() > int64(page.Speed) {
+		result.Reason = "🐌 Server responded successfully, but it was too slow."
+	}
+}
+
+func setTimeTaken(result *types.CheckResult, duration time.Duration) {
+	timeTakenInMicroseconds := duration.Microseconds()
+	result.ResponseTime = float64(timeTakenInMicroseconds) / MicrosecondsInMilliSeconds
+	result.TimeTaken = fmt.Sprintf("%.3f ms", result.ResponseTime)
+
+	if timeTakenInMicroseconds > MicrosecondsInSecond {
+		result.TimeTaken = fmt.Sprintf("%.3f s", float64(timeTakenInMicroseconds)/MicrosecondsInSecond)
 	}
 }
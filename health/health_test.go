@@ -2,12 +2,14 @@
 package health
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,7 +46,35 @@ func TestCheckPage(t *testing.T) {
 			},
 			page: config.Page{
 				URL:    "", // Will be set to test server URL
-				Status: http.StatusCreated,
+				Status: config.ExpectStatus(http.StatusCreated),
+			},
+			expectedStatus: true,
+		},
+		{
+			name: "status list check",
+			serverHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}),
+			cfg: &config.Config{
+				Timeout: 5000,
+			},
+			page: config.Page{
+				URL:    "", // Will be set to test server URL
+				Status: config.ExpectStatus(http.StatusOK, http.StatusNoContent),
+			},
+			expectedStatus: true,
+		},
+		{
+			name: "expect redirect",
+			serverHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/login", http.StatusFound)
+			}),
+			cfg: &config.Config{
+				Timeout: 5000,
+			},
+			page: config.Page{
+				URL:    "", // Will be set to test server URL
+				Status: config.ExpectedStatus{{Min: 300, Max: 399}},
 			},
 			expectedStatus: true,
 		},
@@ -108,7 +138,25 @@ func TestCheckPage(t *testing.T) {
 			},
 			page: config.Page{
 				URL:    "", // Will be set to test server URL
-				Status: http.StatusOK,
+				Status: config.ExpectStatus(http.StatusOK),
+			},
+			expectedStatus: false,
+		},
+		{
+			name: "JSON assertion failed",
+			serverHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"status":"ok","db":"degraded"}`))
+			}),
+			cfg: &config.Config{
+				Timeout: 5000,
+			},
+			page: config.Page{
+				URL: "", // Will be set to test server URL
+				Assertions: []config.Assertion{
+					{Path: "$.status", Operator: config.OpEquals, Value: "ok"},
+					{Path: "$.db", Operator: config.OpEquals, Value: "ok"},
+				},
 			},
 			expectedStatus: false,
 		},
@@ -135,7 +183,7 @@ func TestCheckPage(t *testing.T) {
 			defer server.Close()
 
 			tt.page.URL = server.URL
-			result := checkPage(tt.cfg, tt.page)
+			result := checkPage(context.Background(), tt.cfg, tt.page)
 
 			assert.Equal(t, tt.expectedStatus, result.Status)
 			assert.NotEmpty(t, result.TimeTaken)
@@ -161,7 +209,7 @@ func TestCheckPage_Timeout(t *testing.T) {
 		URL: server.URL,
 	}
 
-	result := checkPage(cfg, page)
+	result := checkPage(context.Background(), cfg, page)
 	assert.False(t, result.Status)
 	assert.Equal(t, 0, result.StatusCode)
 	assert.Equal(t, "0", result.TimeTaken)
@@ -175,7 +223,7 @@ func TestCheckPage_InvalidURL(t *testing.T) {
 		URL: "invalid-url",
 	}
 
-	result := checkPage(cfg, page)
+	result := checkPage(context.Background(), cfg, page)
 	assert.False(t, result.Status)
 	assert.Equal(t, 0, result.StatusCode)
 	assert.Equal(t, "0", result.TimeTaken)
@@ -203,7 +251,133 @@ func TestCheckPage_CustomRequest(t *testing.T) {
 		},
 	}
 
-	result := checkPage(cfg, page)
+	result := checkPage(context.Background(), cfg, page)
 	assert.True(t, result.Status)
 	assert.Equal(t, http.StatusOK, result.StatusCode)
 }
+
+func TestCheckPage_Identity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Timeout: 5000,
+	}
+	page := config.Page{
+		ID:   "home",
+		Name: "Home page",
+		Tags: []string{"web"},
+		URL:  server.URL,
+	}
+
+	result := checkPage(context.Background(), cfg, page)
+	assert.Equal(t, "home", result.ID)
+	assert.Equal(t, "Home page", result.Name)
+	assert.Equal(t, []string{"web"}, result.Tags)
+}
+
+func TestCheckPage_Context(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/body" {
+			w.Write([]byte("partial"))
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-hang:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+
+	tests := []struct {
+		name   string
+		path   string
+		ctx    func() (context.Context, context.CancelFunc)
+		reason string
+	}{
+		{
+			name: "deadline before the response",
+			path: "/",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			reason: "⏱️ Check did not finish before its deadline",
+		},
+		{
+			name: "deadline while reading the body",
+			path: "/body",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			reason: "⏱️ Check did not finish before its deadline",
+		},
+		{
+			name: "cancelled",
+			path: "/",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			reason: "🛑 Check was cancelled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+
+			start := time.Now()
+			result := checkPage(ctx, cfg, config.Page{URL: server.URL + tt.path, TextToInclude: "done"})
+			assert.Less(t, time.Since(start), time.Second)
+			assert.False(t, result.Status)
+			assert.Equal(t, tt.reason, result.Reason)
+		})
+	}
+}
+
+func TestCheck_Context(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/hang" {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Timeout:    5000,
+		RunTimeout: 1,
+		Pages: []config.Page{
+			{ID: "ok", URL: server.URL},
+			{ID: "hung", URL: server.URL + "/hang"},
+		},
+	}
+
+	// A hung page fails at its deadline without holding up the others
+	s := store.NewStore()
+	start := time.Now()
+	Check(context.Background(), cfg, s)
+	assert.Less(t, time.Since(start), 2*time.Second)
+	results := s.GetResults()
+	assert.True(t, results["ok"].Status)
+	assert.False(t, results["hung"].Status)
+	assert.Equal(t, "⏱️ Check did not finish before its deadline", results["hung"].Reason)
+
+	// Cancelled checks aren't recorded as failures
+	s = store.NewStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	Check(ctx, cfg, s)
+	_, recorded := s.GetResults()["hung"]
+	assert.False(t, recorded)
+	assert.Empty(t, s.GetIncidents())
+}
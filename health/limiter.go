@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/marshallku/statusy/config"
+)
+
+// limiter hands out slots to run checks, so that at most a fixed number of
+// checks run at once, both overall and per host.
+type limiter struct {
+	slots   chan struct{}
+	perHost int
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{}
+}
+
+func newLimiter(concurrency config.Concurrency) *limiter {
+	return &limiter{
+		slots:   make(chan struct{}, concurrency.Limit()),
+		perHost: concurrency.MaxChecksPerHost,
+		hosts:   make(map[string]chan struct{}),
+	}
+}
+
+// hostSlots returns the slots of host, or nil when hosts aren't limited.
+func (l *limiter) hostSlots(host string) chan struct{} {
+	if l.perHost == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = make(chan struct{}, l.perHost)
+		l.hosts[host] = slots
+	}
+	return slots
+}
+
+// tryAcquire takes a slot for a check of host if one is free right away.
+func (l *limiter) tryAcquire(host string) bool {
+	hostSlots := l.hostSlots(host)
+	if hostSlots != nil {
+		select {
+		case hostSlots <- struct{}{}:
+		default:
+			return false
+		}
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+		if hostSlots != nil {
+			<-hostSlots
+		}
+		return false
+	}
+}
+
+// acquire waits for a slot for a check of host until ctx is done. The host's
+// slot is taken first so that checks waiting on a busy host don't hold up
+// checks of other hosts.
+func (l *limiter) acquire(ctx context.Context, host string) error {
+	hostSlots := l.hostSlots(host)
+	if hostSlots != nil {
+		select {
+		case hostSlots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		if hostSlots != nil {
+			<-hostSlots
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees the slot taken for a check of host.
+func (l *limiter) release(host string) {
+	<-l.slots
+	if hostSlots := l.hostSlots(host); hostSlots != nil {
+		<-hostSlots
+	}
+}
+
+// pageHost returns the host a page is checked on, the first step's for pages
+// made of steps.
+func pageHost(page config.Page) string {
+	target := page.URL
+	if target == "" && len(page.Steps) > 0 {
+		target = page.Steps[0].URL
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(config.Concurrency{MaxChecks: 2, MaxChecksPerHost: 1})
+
+	assert.True(t, l.tryAcquire("a.example.com"))
+	// The host is busy
+	assert.False(t, l.tryAcquire("a.example.com"))
+	assert.True(t, l.tryAcquire("b.example.com"))
+	// All slots are taken, and the host slot isn't kept
+	assert.False(t, l.tryAcquire("c.example.com"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.acquire(ctx, "c.example.com"), context.DeadlineExceeded)
+
+	l.release("a.example.com")
+	assert.True(t, l.tryAcquire("c.example.com"))
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- l.acquire(context.Background(), "a.example.com")
+	}()
+	l.release("b.example.com")
+	assert.NoError(t, <-acquired)
+}
+
+func TestPageHost(t *testing.T) {
+	assert.Equal(t, "example.com", pageHost(config.Page{URL: "https://example.com:8443/health"}))
+	assert.Equal(t, "api.example.com", pageHost(config.Page{
+		Type:  config.TypeSteps,
+		Steps: []config.Step{{Page: config.Page{URL: "https://api.example.com/login"}}},
+	}))
+	assert.Equal(t, "", pageHost(config.Page{}))
+}
+
+// newConcurrencyServer starts a slow server and returns it along with a
+// function reporting the most requests it handled at once.
+func newConcurrencyServer(t *testing.T) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return peak
+	}
+}
+
+func TestCheck_Concurrency(t *testing.T) {
+	server, peak := newConcurrencyServer(t)
+
+	// More pages wait for a slot than a subscriber's queue holds
+	cfg := &config.Config{Timeout: 5000, Concurrency: config.Concurrency{MaxChecks: 3}}
+	for i := range store.SubscriberBufferSize + 10 {
+		cfg.Pages = append(cfg.Pages, config.Page{ID: fmt.Sprintf("page-%d", i), URL: server.URL})
+	}
+
+	s := store.NewStore()
+	subscriber, _ := s.Subscribe(store.Filter{}, 0)
+	defer s.Hub().Unsubscribe(subscriber)
+	var pending []store.Message
+	received := make(chan struct{})
+	go func() {
+		defer close(received)
+		for message := range subscriber.Messages() {
+			if message.Type == store.MessagePending {
+				pending = append(pending, message)
+			}
+		}
+	}()
+
+	Check(context.Background(), cfg, s)
+
+	assert.Equal(t, 3, peak())
+	for _, result := range s.GetResults() {
+		assert.True(t, result.Status)
+		assert.False(t, result.Pending)
+	}
+
+	// The checks that had to wait were announced as pending together, and
+	// the subscriber kept up
+	assert.Equal(t, 1, s.Hub().Len())
+	s.Hub().Unsubscribe(subscriber)
+	<-received
+	assert.Len(t, pending, 1)
+	assert.Len(t, pending[0].Data, len(cfg.Pages)-3)
+}
+
+func TestCheck_ConcurrencyPerHost(t *testing.T) {
+	server, peak := newConcurrencyServer(t)
+
+	cfg := &config.Config{Timeout: 5000, Concurrency: config.Concurrency{MaxChecksPerHost: 2}}
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		cfg.Pages = append(cfg.Pages, config.Page{ID: id, URL: server.URL})
+	}
+
+	Check(context.Background(), cfg, store.NewStore())
+	assert.Equal(t, 2, peak())
+}
@@ -0,0 +1,51 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/types"
+)
+
+// redirectTracker applies the redirect policy of a page and records every
+// redirect it sees.
+type redirectTracker struct {
+	page  config.Page
+	chain []types.Redirect
+	err   error
+}
+
+func (t *redirectTracker) checkRedirect(req *http.Request, via []*http.Request) error {
+	previous := via[len(via)-1]
+	t.chain = append(t.chain, types.Redirect{
+		URL:        previous.URL.String(),
+		StatusCode: req.Response.StatusCode,
+		Location:   req.URL.String(),
+	})
+
+	switch {
+	case previous.URL.Scheme == "https" && req.URL.Scheme == "http" && !t.page.AllowInsecureRedirects:
+		t.err = fmt.Errorf("redirected from HTTPS to HTTP: %s → %s", printable(previous.URL.String()), printable(req.URL.String()))
+	case !t.page.ShouldFollowRedirects():
+	case len(t.chain) > t.page.RedirectLimit():
+		t.err = fmt.Errorf("stopped after %d redirects", t.page.RedirectLimit())
+	default:
+		return nil
+	}
+
+	// Stop here and evaluate the redirect response itself
+	return http.ErrUseLastResponse
+}
+
+// checkFinalURL verifies where the page ended up after redirects.
+func (t *redirectTracker) checkFinalURL(resp *http.Response) error {
+	if t.err != nil {
+		return t.err
+	}
+
+	if t.page.FinalURL != "" && resp.Request.URL.String() != t.page.FinalURL {
+		return fmt.Errorf("ended up on %s, expected %s", printable(resp.Request.URL.String()), t.page.FinalURL)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedirectServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/step", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/step", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheckPage_RedirectChain(t *testing.T) {
+	server := newRedirectServer()
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+	result := checkPage(context.Background(), cfg, config.Page{URL: server.URL + "/"})
+
+	assert.True(t, result.Status)
+	assert.Equal(t, server.URL+"/login", result.FinalURL)
+	assert.Len(t, result.Redirects, 2)
+	assert.Equal(t, server.URL+"/", result.Redirects[0].URL)
+	assert.Equal(t, http.StatusMovedPermanently, result.Redirects[0].StatusCode)
+	assert.Equal(t, server.URL+"/step", result.Redirects[0].Location)
+	assert.Equal(t, http.StatusFound, result.Redirects[1].StatusCode)
+}
+
+func TestCheckPage_RedirectPolicy(t *testing.T) {
+	server := newRedirectServer()
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+	noFollow := false
+
+	tests := []struct {
+		name           string
+		page           config.Page
+		expectedStatus bool
+		statusCode     int
+	}{
+		{
+			name:           "final URL matches",
+			page:           config.Page{URL: server.URL + "/step", FinalURL: server.URL + "/login"},
+			expectedStatus: true,
+			statusCode:     http.StatusOK,
+		},
+		{
+			name:           "final URL mismatch",
+			page:           config.Page{URL: server.URL + "/", FinalURL: server.URL + "/"},
+			expectedStatus: false,
+			statusCode:     http.StatusOK,
+		},
+		{
+			name:           "too many redirects",
+			page:           config.Page{URL: server.URL + "/", MaxRedirects: 1},
+			expectedStatus: false,
+			statusCode:     http.StatusFound,
+		},
+		{
+			name:           "redirects not followed",
+			page:           config.Page{URL: server.URL + "/", FollowRedirects: &noFollow},
+			expectedStatus: false,
+			statusCode:     http.StatusMovedPermanently,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := checkPage(context.Background(), cfg, tt.page)
+			assert.Equal(t, tt.expectedStatus, result.Status)
+			assert.Equal(t, tt.statusCode, result.StatusCode)
+		})
+	}
+}
+
+func TestRedirectTracker_Downgrade(t *testing.T) {
+	from, _ := url.Parse("https://example.com/")
+	to, _ := url.Parse("http://example.com/")
+	via := []*http.Request{{URL: from}}
+	next := &http.Request{URL: to, Response: &http.Response{StatusCode: http.StatusMovedPermanently}}
+
+	tracker := &redirectTracker{page: config.Page{}}
+	assert.ErrorIs(t, tracker.checkRedirect(next, via), http.ErrUseLastResponse)
+	assert.Error(t, tracker.err)
+
+	tracker = &redirectTracker{page: config.Page{AllowInsecureRedirects: true}}
+	assert.NoError(t, tracker.checkRedirect(next, via))
+	assert.NoError(t, tracker.err)
+}
+
+func TestRedirectTracker_DowngradeReason(t *testing.T) {
+	from, _ := url.Parse("https://example.com/")
+	to, _ := url.Parse("http://example.com/?q=" + strings.Repeat("a", 500))
+	via := []*http.Request{{URL: from}}
+	next := &http.Request{URL: to, Response: &http.Response{StatusCode: http.StatusFound}}
+
+	tracker := &redirectTracker{page: config.Page{}}
+	assert.ErrorIs(t, tracker.checkRedirect(next, via), http.ErrUseLastResponse)
+	assert.Less(t, len(tracker.err.Error()), 300)
+}
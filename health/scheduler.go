@@ -0,0 +1,186 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/types"
+)
+
+// Scheduler checks each page on its own schedule: every check_interval while
+// it is up and every down_interval while it is down, backing off once it has
+// been down for a while. Pages are checked independently, so a slow check
+// only holds up the next check of its own page.
+type Scheduler struct {
+	cfg     *config.Config
+	store   *store.Store
+	checker *checker
+	// rescheduled is signalled when a check moves a page's next check
+	rescheduled chan struct{}
+
+	mu    sync.Mutex
+	pages map[string]*schedule
+}
+
+// schedule tracks when a page is checked next.
+type schedule struct {
+	next      time.Time
+	interval  time.Duration
+	downSince time.Time
+	// backoff is the interval while backing off, 0 until then
+	backoff time.Duration
+	// checking is set while a check of the page is running
+	checking bool
+}
+
+// NewScheduler returns a scheduler with every page due right away.
+func NewScheduler(cfg *config.Config, store *store.Store) *Scheduler {
+	pages := make(map[string]*schedule, len(cfg.Pages))
+	for _, page := range cfg.Pages {
+		pages[page.ID] = &schedule{interval: cfg.Interval()}
+	}
+	return &Scheduler{
+		cfg:         cfg,
+		store:       store,
+		checker:     newChecker(cfg, store),
+		rescheduled: make(chan struct{}, 1),
+		pages:       pages,
+	}
+}
+
+// Run starts checking the pages due at now in the background. Pages still
+// being checked are left out until their check is done.
+func (s *Scheduler) Run(ctx context.Context, now time.Time) {
+	s.checker.start(ctx, s.due(now), s.interval, s.record)
+}
+
+// Wait returns once every check started by Run is done.
+func (s *Scheduler) Wait() {
+	s.checker.wait()
+}
+
+// Rescheduled is signalled when a finished check has scheduled the next
+// check of its page, which may be due before Next returned earlier.
+func (s *Scheduler) Rescheduled() <-chan struct{} {
+	return s.rescheduled
+}
+
+// interval returns the interval page is currently checked at.
+func (s *Scheduler) interval(page config.Page) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pages[page.ID].interval
+}
+
+// due returns the pages due at now and marks them as being checked. They are
+// scheduled again at their current interval, which record adjusts once the
+// check is done; a page whose check is dropped is retried then.
+func (s *Scheduler) due(now time.Time) []config.Page {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pages []config.Page
+	for _, page := range s.cfg.Pages {
+		schedule := s.pages[page.ID]
+		if schedule.checking || schedule.next.After(now) {
+			continue
+		}
+		schedule.next = now.Add(schedule.interval)
+		schedule.checking = true
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// Next returns when the next page that isn't being checked is due.
+func (s *Scheduler) Next() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next time.Time
+	for _, schedule := range s.pages {
+		if schedule.checking {
+			continue
+		}
+		if next.IsZero() || schedule.next.Before(next) {
+			next = schedule.next
+		}
+	}
+	if next.IsZero() {
+		return time.Now().Add(s.cfg.Interval())
+	}
+	return next
+}
+
+// record schedules the next check of page from the result of its last one.
+// A dropped check, with ok false, leaves the schedule as it is.
+func (s *Scheduler) record(page config.Page, result types.CheckResult, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule := s.pages[page.ID]
+	schedule.checking = false
+	defer s.reschedule()
+	if !ok {
+		return
+	}
+
+	if result.Status {
+		schedule.interval = s.cfg.Interval()
+		schedule.downSince = time.Time{}
+		schedule.backoff = 0
+	} else {
+		if schedule.downSince.IsZero() {
+			schedule.downSince = result.LastChecked
+		}
+		schedule.interval = s.cfg.DownIntervalFor(page)
+
+		backoff := s.cfg.BackoffFor(page)
+		if backoff != nil && result.LastChecked.Sub(schedule.downSince) >= backoff.Delay() {
+			if schedule.backoff == 0 {
+				schedule.backoff = schedule.interval
+			}
+			schedule.backoff = backoff.Next(schedule.backoff)
+			schedule.interval = schedule.backoff
+		}
+	}
+	schedule.next = result.LastChecked.Add(schedule.interval)
+
+	// Escalations are sent after checks, so a page that is down is checked
+	// again in time for the next one. Only that check moves: the interval,
+	// which also bounds how long checks may take, stays the same
+	if !result.Status {
+		if deadline, ok := s.escalationDeadline(page); ok && deadline.After(result.LastChecked) && deadline.Before(schedule.next) {
+			schedule.next = deadline
+		}
+	}
+}
+
+// reschedule signals Rescheduled without blocking; one pending signal is
+// enough to make the caller look at Next again.
+func (s *Scheduler) reschedule() {
+	select {
+	case s.rescheduled <- struct{}{}:
+	default:
+	}
+}
+
+// escalationDeadline returns when the next escalation step of the page's
+// open incident is due, if any.
+func (s *Scheduler) escalationDeadline(page config.Page) (time.Time, bool) {
+	if s.store == nil {
+		return time.Time{}, false
+	}
+	incident, ok := s.store.GetOpenIncident(page.ID)
+	if !ok || incident.Acknowledged {
+		return time.Time{}, false
+	}
+	steps := s.cfg.EscalationPolicy(page)
+	if incident.EscalationLevel >= len(steps) {
+		return time.Time{}, false
+	}
+	return incident.StartedAt.Add(steps[incident.EscalationLevel].Delay()), true
+}
@@ -0,0 +1,273 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/store"
+	"github.com/marshallku/statusy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler_Intervals(t *testing.T) {
+	page := config.Page{ID: "api", URL: "https://example.com"}
+	cfg := &config.Config{
+		CheckInterval: 60,
+		DownInterval:  10,
+		Backoff:       &config.Backoff{After: 30, MaxInterval: 100},
+		Pages:         []config.Page{page},
+	}
+	s := NewScheduler(cfg, nil)
+	start := time.Now()
+
+	// Every page is due right away
+	assert.Equal(t, []config.Page{page}, s.due(start))
+	assert.Empty(t, s.due(start))
+
+	now := start
+	check := func(status bool) time.Duration {
+		s.record(page, types.CheckResult{ID: page.ID, Status: status, LastChecked: now}, true)
+		interval := s.Next().Sub(now)
+		now = now.Add(interval)
+		return interval
+	}
+
+	assert.Equal(t, 60*time.Second, check(true))
+	// Down pages are checked every down_interval until they have been down
+	// for backoff.after
+	assert.Equal(t, 10*time.Second, check(false))
+	assert.Equal(t, 10*time.Second, check(false))
+	assert.Equal(t, 10*time.Second, check(false))
+	// Then the interval doubles up to backoff.max_interval
+	assert.Equal(t, 20*time.Second, check(false))
+	assert.Equal(t, 40*time.Second, check(false))
+	assert.Equal(t, 80*time.Second, check(false))
+	assert.Equal(t, 100*time.Second, check(false))
+	assert.Equal(t, 100*time.Second, check(false))
+	// Recovering resets the schedule
+	assert.Equal(t, 60*time.Second, check(true))
+	assert.Equal(t, 10*time.Second, check(false))
+}
+
+func TestScheduler_PageOverrides(t *testing.T) {
+	fast := config.Page{ID: "fast", URL: "https://example.com", DownInterval: 5}
+	slow := config.Page{ID: "slow", URL: "https://example.org", Backoff: &config.Backoff{Multiplier: 3, MaxInterval: 600}}
+	plain := config.Page{ID: "plain", URL: "https://example.net"}
+	cfg := &config.Config{CheckInterval: 60, Pages: []config.Page{fast, slow, plain}}
+	s := NewScheduler(cfg, nil)
+	now := time.Now()
+	s.due(now)
+
+	s.record(fast, types.CheckResult{LastChecked: now}, true)
+	s.record(slow, types.CheckResult{LastChecked: now}, true)
+	s.record(plain, types.CheckResult{LastChecked: now}, true)
+
+	assert.Equal(t, now.Add(5*time.Second), s.Next())
+	assert.Equal(t, now.Add(5*time.Second), s.pages["fast"].next)
+	// Without a down_interval, pages that are down are checked every
+	// check_interval, backing off right away when backoff.after is 0
+	assert.Equal(t, now.Add(60*time.Second), s.pages["plain"].next)
+	assert.Equal(t, now.Add(180*time.Second), s.pages["slow"].next)
+}
+
+func TestScheduler_Run(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Timeout:       5000,
+		CheckInterval: 60,
+		DownInterval:  5,
+		Pages:         []config.Page{{ID: "api", URL: server.URL}},
+	}
+	st := store.NewStore()
+	s := NewScheduler(cfg, st)
+
+	start := time.Now()
+	s.Run(context.Background(), start)
+	s.Wait()
+	assert.True(t, st.GetResults()["api"].Status)
+	assert.WithinDuration(t, start.Add(60*time.Second), s.Next(), time.Second)
+
+	// Nothing is due yet
+	s.Run(context.Background(), start.Add(30*time.Second))
+	s.Wait()
+	assert.Len(t, st.GetHistory(), 1)
+
+	down.Store(true)
+	s.Run(context.Background(), start.Add(2*time.Minute))
+	s.Wait()
+	assert.False(t, st.GetResults()["api"].Status)
+	assert.WithinDuration(t, time.Now().Add(5*time.Second), s.Next(), time.Second)
+}
+
+func TestScheduler_Deadlines(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			time.Sleep(1500 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Timeout:       5000,
+		CheckInterval: 60,
+		Pages: []config.Page{
+			{ID: "down", URL: server.URL + "/down", DownInterval: 1},
+			{ID: "slow", URL: server.URL + "/slow"},
+		},
+	}
+	st := store.NewStore()
+	s := NewScheduler(cfg, st)
+
+	// Each check's deadline follows its own page's interval, so the short
+	// down_interval of one page doesn't cut off checks of the others
+	start := time.Now()
+	s.Run(context.Background(), start)
+	s.Wait()
+	s.Run(context.Background(), start.Add(2*time.Minute))
+	s.Wait()
+	results := st.GetResults()
+	assert.False(t, results["down"].Status)
+	assert.True(t, results["slow"].Status, results["slow"].Reason)
+}
+
+func TestScheduler_Escalation(t *testing.T) {
+	page := config.Page{ID: "api", URL: "https://example.com"}
+	cfg := &config.Config{
+		CheckInterval: 60,
+		Backoff:       &config.Backoff{MaxInterval: 3600},
+		Escalation: []config.EscalationStep{
+			{After: 5, WebhookURL: "https://hooks.example.com/first"},
+			{After: 30, WebhookURL: "https://hooks.example.com/second"},
+		},
+		Pages: []config.Page{page},
+	}
+	st := store.NewStore()
+	s := NewScheduler(cfg, st)
+	start := time.Now()
+	s.due(start)
+
+	now := start
+	check := func() time.Duration {
+		result := types.CheckResult{ID: page.ID, LastChecked: now}
+		st.UpdateResult(result)
+		s.record(page, result, true)
+		interval := s.pages[page.ID].next.Sub(now)
+		now = now.Add(interval)
+		return interval
+	}
+
+	// Backing off doesn't delay the next escalation step
+	assert.Equal(t, 2*time.Minute, check())
+	assert.Equal(t, 3*time.Minute, check())
+	// The interval itself keeps backing off
+	assert.Equal(t, 4*time.Minute, s.pages[page.ID].interval)
+
+	incident, _ := st.GetOpenIncident(page.ID)
+	st.SetEscalationLevel(incident.ID, 1)
+	assert.Equal(t, 8*time.Minute, check())
+
+	// Without escalation steps left, the backoff applies again
+	st.SetEscalationLevel(incident.ID, 2)
+	assert.Equal(t, 16*time.Minute, check())
+}
+
+func TestScheduler_EscalationDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	page := config.Page{ID: "api", URL: server.URL}
+	cfg := &config.Config{
+		Timeout:       5000,
+		CheckInterval: 600,
+		Escalation:    []config.EscalationStep{{After: 1, WebhookURL: "http://127.0.0.1:1"}},
+		Pages:         []config.Page{page},
+	}
+	st := store.NewStore()
+	s := NewScheduler(cfg, st)
+	now := time.Now()
+	s.due(now)
+
+	// The incident started a minute ago and the last check failed just
+	// 100ms before its first escalation step is due
+	st.UpdateResult(types.CheckResult{ID: page.ID, LastChecked: now.Add(-time.Minute)})
+	failed := types.CheckResult{ID: page.ID, LastChecked: now.Add(-100 * time.Millisecond)}
+	st.UpdateResult(failed)
+	s.record(page, failed, true)
+	assert.Equal(t, now, s.pages[page.ID].next)
+
+	// The recheck is still given the page's whole interval, even though it
+	// takes longer than the wait for the escalation
+	s.Run(context.Background(), now)
+	s.Wait()
+	result := st.GetResults()[page.ID]
+	assert.True(t, result.Status, result.Reason)
+}
+
+func TestScheduler_Independent(t *testing.T) {
+	release := make(chan struct{})
+	var slowRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/slow":
+			slowRequests.Add(1)
+			<-release
+		}
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		Timeout:       5000,
+		CheckInterval: 60,
+		Pages: []config.Page{
+			{ID: "down", URL: server.URL + "/down", DownInterval: 1},
+			{ID: "slow", URL: server.URL + "/slow"},
+		},
+	}
+	st := store.NewStore()
+	s := NewScheduler(cfg, st)
+	defer s.Wait()
+	defer close(release)
+
+	checks := func(id string) int {
+		count := 0
+		for _, entry := range st.GetHistory() {
+			if entry.ID == id {
+				count++
+			}
+		}
+		return count
+	}
+
+	// Run doesn't wait for the checks it starts
+	s.Run(context.Background(), time.Now())
+	assert.Eventually(t, func() bool { return checks("down") == 1 }, time.Second, 10*time.Millisecond)
+	<-s.Rescheduled()
+
+	// The page that is down is checked again while the slow page's check is
+	// still running, which isn't started a second time
+	next := s.Next()
+	assert.WithinDuration(t, time.Now().Add(time.Second), next, time.Second)
+	s.Run(context.Background(), next)
+	assert.Eventually(t, func() bool { return checks("down") == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 0, checks("slow"))
+	assert.Equal(t, int32(1), slowRequests.Load())
+}
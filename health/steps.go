@@ -0,0 +1,201 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/marshallku/statusy/jsonpath"
+	"github.com/marshallku/statusy/types"
+)
+
+var variablePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// checkSteps runs the steps of a page in order, sharing cookies and captured
+// variables between them, and stops at the first step that fails.
+func checkSteps(ctx context.Context, cfg *config.Config, page config.Page, result *types.CheckResult) {
+	jar, _ := cookiejar.New(nil)
+	variables := make(map[string]string, len(page.Variables))
+	for name, value := range page.Variables {
+		variables[name] = value
+	}
+
+	var total time.Duration
+	var warning string
+
+	for i, step := range page.Steps {
+		step = expandStep(step, variables)
+		if step.TLS == nil {
+			step.TLS = page.TLS
+		}
+		if step.Network == nil {
+			step.Network = page.Network
+		}
+		if step.KeepAlive == nil {
+			step.KeepAlive = page.KeepAlive
+		}
+		if step.HTTP2 == nil {
+			step.HTTP2 = page.HTTP2
+		}
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		stepResult := types.CheckResult{URL: step.URL, TimeTaken: "0"}
+		resp, body := checkRequest(ctx, cfg, step.Page, jar, capturesBody(step.Capture), &stepResult)
+
+		total += time.Duration(stepResult.ResponseTime * float64(time.Millisecond))
+		result.StatusCode = stepResult.StatusCode
+		result.Steps = append(result.Steps, types.StepResult{
+			Name:         name,
+			URL:          step.URL,
+			StatusCode:   stepResult.StatusCode,
+			TimeTaken:    stepResult.TimeTaken,
+			ResponseTime: stepResult.ResponseTime,
+			Status:       stepResult.Status,
+			Reason:       stepResult.Reason,
+		})
+		if result.URL == "" {
+			result.URL = step.URL
+		}
+
+		if !stepResult.Status {
+			setTimeTaken(result, total)
+			result.Reason = fmt.Sprintf("%s (step %s)", stepResult.Reason, name)
+			return
+		}
+		if warning == "" && stepResult.Reason != "" {
+			warning = fmt.Sprintf("%s (step %s)", stepResult.Reason, name)
+		}
+
+		if err := capture(step.Capture, resp, body, jar, variables); err != nil {
+			setTimeTaken(result, total)
+			result.Reason = fmt.Sprintf("🧩 Failed to capture variable: %v (step %s)", err, name)
+			return
+		}
+	}
+
+	setTimeTaken(result, total)
+	result.Status = true
+	result.Reason = warning
+	checkSpeed(page, total, result)
+}
+
+// expandStep replaces ${name} in the URL, headers and body of a step with
+// captured variables. Unknown variables are left untouched.
+func expandStep(step config.Step, variables map[string]string) config.Step {
+	expand := func(s string) string {
+		return variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+			if value, ok := variables[match[2:len(match)-1]]; ok {
+				return value
+			}
+			return match
+		})
+	}
+
+	step.URL = expand(step.URL)
+	step.FinalURL = expand(step.FinalURL)
+	if step.Request != nil {
+		request := *step.Request
+		request.Body = expand(request.Body)
+		request.Headers = make(map[string]string, len(step.Request.Headers))
+		for key, value := range step.Request.Headers {
+			request.Headers[key] = expand(value)
+		}
+		step.Request = &request
+	}
+	return step
+}
+
+// capturesBody reports whether any capture reads the response body.
+func capturesBody(captures []config.Capture) bool {
+	for _, c := range captures {
+		if c.ReadsBody() {
+			return true
+		}
+	}
+	return false
+}
+
+func capture(captures []config.Capture, resp *http.Response, body []byte, jar http.CookieJar, variables map[string]string) error {
+	var data interface{}
+	parsed := false
+
+	for _, c := range captures {
+		var value string
+		var found bool
+
+		switch {
+		case c.JSONPath != "":
+			if !parsed {
+				if err := json.Unmarshal(body, &data); err != nil {
+					return fmt.Errorf("%s: response body is not valid JSON", c.Name)
+				}
+				parsed = true
+			}
+			path, err := jsonpath.Compile(c.JSONPath)
+			if err != nil {
+				return err
+			}
+			if values := path.Find(data); len(values) > 0 {
+				value, found = stringify(values[0]), true
+			}
+		case c.Regex != "":
+			re, err := regexp.Compile(c.Regex)
+			if err != nil {
+				return err
+			}
+			if match := re.FindSubmatch(body); match != nil {
+				value, found = string(match[0]), true
+				if len(match) > 1 {
+					value = string(match[1])
+				}
+			}
+		case c.Header != "":
+			if values := resp.Header.Values(c.Header); len(values) > 0 {
+				value, found = values[0], true
+			}
+		case c.Cookie != "":
+			value, found = findCookie(c.Cookie, resp, jar)
+		}
+
+		if !found {
+			return fmt.Errorf("%s not found in response", c.Name)
+		}
+		variables[c.Name] = value
+	}
+	return nil
+}
+
+func findCookie(name string, resp *http.Response, jar http.CookieJar) (string, bool) {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			return cookie.Value, true
+		}
+	}
+	for _, cookie := range jar.Cookies(resp.Request.URL) {
+		if cookie.Name == name {
+			return cookie.Value, true
+		}
+	}
+	return "", false
+}
+
+// stringify turns a JSON value into the text substituted for a variable.
+func stringify(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return strings.TrimSpace(string(encoded))
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTransactionServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		var credentials map[string]string
+		json.NewDecoder(r.Body).Decode(&credentials)
+		if credentials["user"] != "jane" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3ss10n", Path: "/"})
+		w.Header().Set("X-Request-Id", "42")
+		w.Write([]byte(`{"token":"t0k3n","user":{"id":7}}`))
+	})
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if r.Header.Get("Authorization") != "Bearer t0k3n" || err != nil || cookie.Value != "s3ss10n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`<p>user ` + r.PathValue("id") + ` request ` + r.URL.Query().Get("request") + `</p>`))
+	})
+	mux.HandleFunc("POST /logout", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestCheckPage_Steps(t *testing.T) {
+	server := newTransactionServer()
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+	page := config.Page{
+		ID:        "login-flow",
+		Type:      config.TypeSteps,
+		Variables: map[string]string{"user": "jane"},
+		Steps: []config.Step{
+			{
+				Page: config.Page{
+					Name:    "login",
+					URL:     server.URL + "/login",
+					Request: &config.Request{Method: "POST", Body: `{"user":"${user}"}`},
+				},
+				Capture: []config.Capture{
+					{Name: "token", JSONPath: "$.token"},
+					{Name: "userId", JSONPath: "$.user.id"},
+					{Name: "requestId", Header: "X-Request-Id"},
+					{Name: "session", Cookie: "session"},
+				},
+			},
+			{
+				Page: config.Page{
+					Name: "profile",
+					URL:  server.URL + "/users/${userId}?request=${requestId}",
+					Request: &config.Request{
+						Method:  "GET",
+						Headers: map[string]string{"Authorization": "Bearer ${token}"},
+					},
+					TextToInclude: "user 7 request 42",
+				},
+				Capture: []config.Capture{
+					{Name: "shownId", Regex: `user (\d+)`},
+				},
+			},
+			{
+				Page: config.Page{
+					Name:    "logout",
+					URL:     server.URL + "/logout",
+					Request: &config.Request{Method: "POST", Body: "${session} ${shownId}"},
+					Status:  config.ExpectStatus(http.StatusNoContent),
+				},
+			},
+		},
+	}
+
+	result := checkPage(context.Background(), cfg, page)
+	assert.True(t, result.Status, result.Reason)
+	assert.Len(t, result.Steps, 3)
+	assert.Equal(t, "profile", result.Steps[1].Name)
+	assert.Equal(t, server.URL+"/users/7?request=42", result.Steps[1].URL)
+	assert.Equal(t, http.StatusNoContent, result.StatusCode)
+	assert.Equal(t, server.URL+"/login", result.URL)
+}
+
+func TestCheckPage_StepFails(t *testing.T) {
+	server := newTransactionServer()
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+	page := config.Page{
+		Type: config.TypeSteps,
+		Steps: []config.Step{
+			{
+				Page: config.Page{
+					Name:    "login",
+					URL:     server.URL + "/login",
+					Request: &config.Request{Method: "POST", Body: `{"user":"john"}`},
+				},
+			},
+			{
+				Page: config.Page{Name: "never", URL: server.URL + "/users/1"},
+			},
+		},
+	}
+
+	result := checkPage(context.Background(), cfg, page)
+	assert.False(t, result.Status)
+	assert.Len(t, result.Steps, 1)
+	assert.Equal(t, http.StatusUnauthorized, result.StatusCode)
+	assert.Contains(t, result.Reason, "step login")
+}
+
+func TestCheckPage_StepCaptureMissing(t *testing.T) {
+	server := newTransactionServer()
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+	page := config.Page{
+		Type:      config.TypeSteps,
+		Variables: map[string]string{"user": "jane"},
+		Steps: []config.Step{
+			{
+				Page: config.Page{
+					Name:    "login",
+					URL:     server.URL + "/login",
+					Request: &config.Request{Method: "POST", Body: `{"user":"${user}"}`},
+				},
+				Capture: []config.Capture{{Name: "refresh", JSONPath: "$.refresh_token"}},
+			},
+		},
+	}
+
+	result := checkPage(context.Background(), cfg, page)
+	assert.False(t, result.Status)
+	assert.Contains(t, result.Reason, "refresh")
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/marshallku/statusy/config"
+)
+
+// transportPool shares transports between checks so that connections are
+// reused deliberately and the number of open connections stays bounded.
+// Pages with the same connection options share a transport.
+type transportPool struct {
+	mu         sync.Mutex
+	transports map[string]*http.Transport
+}
+
+var transports = &transportPool{
+	transports: make(map[string]*http.Transport),
+}
+
+// transportKey describes every option a transport is built from.
+func transportKey(cfg *config.Config, page config.Page) string {
+	var tlsOptions config.TLS
+	if page.TLS != nil {
+		tlsOptions = *page.TLS
+	}
+	return fmt.Sprintf("%+v|%+v|%+v|%t|%t",
+		cfg.Transport, tlsOptions, cfg.NetworkFor(page), page.KeepAliveEnabled(), page.HTTP2Enabled())
+}
+
+// get returns the transport for page, building it on first use.
+func (p *transportPool) get(cfg *config.Config, page config.Page) (*http.Transport, error) {
+	key := transportKey(cfg, page)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if transport, ok := p.transports[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := newTransport(cfg, page)
+	if err != nil {
+		return nil, err
+	}
+	p.transports[key] = transport
+	return transport, nil
+}
+
+// closeIdleConnections closes the idle connections of every transport.
+func (p *transportPool) closeIdleConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, transport := range p.transports {
+		transport.CloseIdleConnections()
+	}
+}
+
+// CloseIdleConnections closes the connections kept open between checks, e.g.
+// when shutting down.
+func CloseIdleConnections() {
+	transports.closeIdleConnections()
+}
+
+func newTransport(cfg *config.Config, page config.Page) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConns, transport.MaxIdleConnsPerHost = cfg.Transport.IdleConns()
+	transport.MaxConnsPerHost = cfg.Transport.MaxConnsPerHost
+	transport.IdleConnTimeout = cfg.Transport.IdleTimeout()
+	transport.DisableKeepAlives = !page.KeepAliveEnabled()
+
+	if !page.HTTP2Enabled() {
+		// A non-nil, empty map stops the transport from negotiating HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	if page.TLS != nil {
+		tlsConfig, err := newTLSConfig(page.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	if err := configureNetwork(transport, cfg.NetworkFor(page)); err != nil {
+		return nil, err
+	}
+
+	return transport, nil
+}
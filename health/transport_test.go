@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/marshallku/statusy/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportPool_Get(t *testing.T) {
+	pool := &transportPool{transports: make(map[string]*http.Transport)}
+	cfg := &config.Config{}
+	noKeepAlive := false
+
+	first, err := pool.get(cfg, config.Page{URL: "https://example.com"})
+	assert.NoError(t, err)
+	second, err := pool.get(cfg, config.Page{URL: "https://example.org"})
+	assert.NoError(t, err)
+	assert.Same(t, first, second)
+	assert.Equal(t, config.DefaultMaxIdleConns, first.MaxIdleConns)
+	assert.Equal(t, config.DefaultMaxIdleConnsPerHost, first.MaxIdleConnsPerHost)
+
+	cold, err := pool.get(cfg, config.Page{URL: "https://example.com", KeepAlive: &noKeepAlive})
+	assert.NoError(t, err)
+	assert.NotSame(t, first, cold)
+	assert.True(t, cold.DisableKeepAlives)
+}
+
+func TestCheckPage_KeepAlive(t *testing.T) {
+	var connections atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	enabled, disabled := true, false
+	tests := []struct {
+		name        string
+		keepAlive   *bool
+		connections int32
+	}{
+		{name: "default", keepAlive: nil, connections: 1},
+		{name: "enabled", keepAlive: &enabled, connections: 1},
+		{name: "disabled", keepAlive: &disabled, connections: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transports.closeIdleConnections()
+			connections.Store(0)
+
+			cfg := &config.Config{Timeout: 5000}
+			page := config.Page{URL: server.URL, KeepAlive: tt.keepAlive}
+			for i := 0; i < 3; i++ {
+				assert.True(t, checkPage(context.Background(), cfg, page).Status)
+			}
+			assert.Equal(t, tt.connections, connections.Load())
+		})
+	}
+}
+
+func TestCheckPage_HTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	cfg := &config.Config{Timeout: 5000}
+	disabled := false
+	insecure := &config.TLS{InsecureSkipVerify: true}
+
+	result := checkPage(context.Background(), cfg, config.Page{URL: server.URL, TLS: insecure, TextToInclude: "HTTP/2.0"})
+	assert.True(t, result.Status, result.Reason)
+
+	result = checkPage(context.Background(), cfg, config.Page{URL: server.URL, TLS: insecure, HTTP2: &disabled, TextToInclude: "HTTP/1.1"})
+	assert.True(t, result.Status, result.Reason)
+}
@@ -0,0 +1,178 @@
+// Package jsonpath implements the subset of JSONPath needed to select values
+// from decoded JSON documents: $, .key, ['key'], [index], [*], .* and ..key.
+package jsonpath
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPath = errors.New("invalid JSONPath")
+
+type segmentKind int
+
+const (
+	childSegment segmentKind = iota
+	indexSegment
+	wildcardSegment
+	descendantSegment
+)
+
+type segment struct {
+	kind  segmentKind
+	key   string
+	index int
+}
+
+// Path is a compiled JSONPath expression.
+type Path struct {
+	expression string
+	segments   []segment
+}
+
+func (p *Path) String() string {
+	return p.expression
+}
+
+// Compile parses a JSONPath expression. The leading $ is optional.
+func Compile(expression string) (*Path, error) {
+	rest := strings.TrimSpace(expression)
+	rest = strings.TrimPrefix(rest, "$")
+
+	path := &Path{expression: expression}
+	for rest != "" {
+		var seg segment
+		var err error
+
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.kind = descendantSegment
+			seg.key, rest = readName(rest[2:])
+			if seg.key == "" {
+				return nil, fmt.Errorf("%w %q: expected a name after ..", ErrInvalidPath, expression)
+			}
+		case rest[0] == '.':
+			seg.key, rest = readName(rest[1:])
+			switch seg.key {
+			case "":
+				return nil, fmt.Errorf("%w %q: expected a name after .", ErrInvalidPath, expression)
+			case "*":
+				seg.kind = wildcardSegment
+			}
+		case rest[0] == '[':
+			seg, rest, err = readBracket(rest)
+			if err != nil {
+				return nil, fmt.Errorf("%w %q: %v", ErrInvalidPath, expression, err)
+			}
+		default:
+			if len(path.segments) > 0 {
+				return nil, fmt.Errorf("%w %q: unexpected %q", ErrInvalidPath, expression, rest[0])
+			}
+			// Allow paths without the leading $, e.g. "status.db"
+			seg.key, rest = readName(rest)
+		}
+
+		path.segments = append(path.segments, seg)
+	}
+
+	return path, nil
+}
+
+// readName reads a dotted member name up to the next . or [.
+func readName(s string) (string, string) {
+	end := strings.IndexAny(s, ".[")
+	if end == -1 {
+		return s, ""
+	}
+	return s[:end], s[end:]
+}
+
+func readBracket(s string) (segment, string, error) {
+	end := strings.IndexByte(s, ']')
+	if end == -1 {
+		return segment{}, "", errors.New("unterminated [")
+	}
+
+	inner := strings.TrimSpace(s[1:end])
+	rest := s[end+1:]
+
+	switch {
+	case inner == "*":
+		return segment{kind: wildcardSegment}, rest, nil
+	case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+		return segment{kind: childSegment, key: inner[1 : len(inner)-1]}, rest, nil
+	}
+
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return segment{}, "", fmt.Errorf("invalid index %q", inner)
+	}
+	return segment{kind: indexSegment, index: index}, rest, nil
+}
+
+// Find returns every value in data selected by the path. data is expected to
+// be the result of json.Unmarshal into an interface{}.
+func (p *Path) Find(data interface{}) []interface{} {
+	current := []interface{}{data}
+
+	for _, seg := range p.segments {
+		next := make([]interface{}, 0, len(current))
+		for _, value := range current {
+			next = append(next, seg.apply(value)...)
+		}
+		current = next
+	}
+
+	return current
+}
+
+func (seg segment) apply(value interface{}) []interface{} {
+	switch seg.kind {
+	case childSegment:
+		if object, ok := value.(map[string]interface{}); ok {
+			if child, ok := object[seg.key]; ok {
+				return []interface{}{child}
+			}
+		}
+	case indexSegment:
+		if array, ok := value.([]interface{}); ok {
+			index := seg.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index >= 0 && index < len(array) {
+				return []interface{}{array[index]}
+			}
+		}
+	case wildcardSegment:
+		return children(value)
+	case descendantSegment:
+		var found []interface{}
+		child := segment{kind: childSegment, key: seg.key}
+		if seg.key == "*" {
+			child = segment{kind: wildcardSegment}
+		}
+		found = append(found, child.apply(value)...)
+		for _, c := range children(value) {
+			found = append(found, seg.apply(c)...)
+		}
+		return found
+	}
+	return nil
+}
+
+func children(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, child := range v {
+			values = append(values, child)
+		}
+		return values
+	case []interface{}:
+		return v
+	}
+	return nil
+}
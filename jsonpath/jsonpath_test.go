@@ -0,0 +1,57 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const document = `{
+	"status": "ok",
+	"checks": {"db": "degraded", "cache": "ok"},
+	"items": [
+		{"name": "a", "count": 1},
+		{"name": "b", "count": 2}
+	],
+	"weird key": true
+}`
+
+func TestFind(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(document), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected []interface{}
+	}{
+		{"$.status", []interface{}{"ok"}},
+		{"status", []interface{}{"ok"}},
+		{"$.checks.db", []interface{}{"degraded"}},
+		{"$['checks']['cache']", []interface{}{"ok"}},
+		{"$.items[0].name", []interface{}{"a"}},
+		{"$.items[-1].count", []interface{}{2.0}},
+		{"$.items[*].name", []interface{}{"a", "b"}},
+		{"$..count", []interface{}{1.0, 2.0}},
+		{`$["weird key"]`, []interface{}{true}},
+		{"$.missing", []interface{}{}},
+		{"$.items[5]", []interface{}{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			path, err := Compile(tt.path)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, path.Find(data))
+		})
+	}
+}
+
+func TestCompile_Invalid(t *testing.T) {
+	for _, expression := range []string{"$.", "$..", "$[abc]", "$[0"} {
+		_, err := Compile(expression)
+		assert.ErrorIs(t, err, ErrInvalidPath, expression)
+	}
+}
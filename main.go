@@ -1,10 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/marshallku/statusy/config"
@@ -29,23 +34,76 @@ func main() {
 	}
 
 	if *mode == "cli" {
-		health.Check(cfg, nil)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		health.Check(ctx, cfg, nil)
 	} else {
-		store := store.NewStore()
-		server := handler.NewHandler(store)
+		serve(cfg)
+	}
+}
 
-		go func() {
-			if err := server.RegisterRoutes(); err != nil {
-				log.Fatal(err)
-			}
-		}()
+// serve checks the pages as they fall due and serves the web interface until
+// SIGINT or SIGTERM, then shuts down gracefully.
+func serve(cfg *config.Config) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		ticker := time.NewTicker(time.Duration(cfg.CheckInterval) * time.Second)
-		defer ticker.Stop()
+	// Checks get their own context, only cancelled once they've had the
+	// grace period to finish
+	checkCtx, cancelChecks := context.WithCancel(context.Background())
+	defer cancelChecks()
 
-		for {
-			health.Check(cfg, store)
-			<-ticker.C
+	store := store.NewStore()
+	server := handler.NewHandler(cfg, store)
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
 		}
+	}()
+
+	scheduler := health.NewScheduler(cfg, store)
+	for {
+		scheduler.Run(checkCtx, time.Now())
+
+		timer := time.NewTimer(time.Until(scheduler.Next()))
+		select {
+		case <-timer.C:
+		case <-scheduler.Rescheduled():
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			stop()
+			shutdown(cfg, server, scheduler, cancelChecks)
+			return
+		}
+	}
+}
+
+// shutdown waits for the running checks, which send their notifications
+// before finishing, then disconnects clients and stops the server. Checks
+// still running after the configured grace period are cancelled. Results are
+// only kept in memory, so there is nothing else to save.
+func shutdown(cfg *config.Config, server *handler.Handler, scheduler *health.Scheduler, cancelChecks context.CancelFunc) {
+	fmt.Println("Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.GracePeriod())
+	defer cancel()
+
+	running := make(chan struct{})
+	go func() {
+		scheduler.Wait()
+		close(running)
+	}()
+
+	select {
+	case <-running:
+	case <-ctx.Done():
+		fmt.Println("Cancelling running checks")
+		cancelChecks()
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Printf("Failed to shut down the server gracefully: %v\n", err)
 	}
+	health.CloseIdleConnections()
 }
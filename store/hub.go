@@ -0,0 +1,229 @@
+package store
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// SubscriberBufferSize is how many messages may wait for a subscriber
+	// before it is considered too slow and evicted.
+	SubscriberBufferSize = 64
+	// BacklogSize is how many recent messages are kept for subscribers
+	// resuming after a reconnect.
+	BacklogSize = 1000
+)
+
+// Filter selects the messages a subscriber receives. A message matches when
+// its type is one of Types and its monitor is one of Monitors or has one of
+// Tags. Empty fields match everything, and messages that don't belong to a
+// monitor match any monitor and tag.
+type Filter struct {
+	Monitors []string `json:"monitors,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Types    []string `json:"types,omitempty"`
+}
+
+// Matches reports whether message passes the filter. A batch passes when
+// any of its monitors does.
+func (f Filter) Matches(message Message) bool {
+	_, ok := f.narrow(message)
+	return ok
+}
+
+// narrow returns message as a subscriber with the filter receives it, and
+// whether it receives it at all. Batches are cut down to the monitors the
+// filter matches.
+func (f Filter) narrow(message Message) (Message, bool) {
+	if message.batch == nil {
+		return message, f.matches(message)
+	}
+	if !f.wants(message.Type) {
+		return message, false
+	}
+
+	data := make(map[string]interface{}, len(message.batch))
+	for _, entry := range message.batch {
+		if f.matchesMonitor(entry.Monitor, entry.tags) {
+			data[entry.Monitor] = entry.Data
+		}
+	}
+	message.Data = data
+	return message, len(data) > 0
+}
+
+func (f Filter) matches(message Message) bool {
+	if !f.wants(message.Type) {
+		return false
+	}
+	return message.Monitor == "" || f.matchesMonitor(message.Monitor, message.tags)
+}
+
+func (f Filter) wants(messageType string) bool {
+	return len(f.Types) == 0 || slices.Contains(f.Types, messageType)
+}
+
+func (f Filter) matchesMonitor(id string, tags []string) bool {
+	if len(f.Monitors) == 0 && len(f.Tags) == 0 {
+		return true
+	}
+	if slices.Contains(f.Monitors, id) {
+		return true
+	}
+	for _, tag := range tags {
+		if slices.Contains(f.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hub fans messages out to subscribers. Publishing never blocks: every
+// subscriber has its own buffered queue, and a subscriber that lets its queue
+// fill up is dropped so that it can't hold up health checks.
+//
+// Every message gets a sequence number, and the most recent messages are kept
+// so that a subscriber can resume where it left off.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscriber]struct{}
+	backlog     []Message
+	next        uint64
+	closed      bool
+}
+
+// Subscriber receives the messages published to a hub.
+type Subscriber struct {
+	send   chan Message
+	filter Filter
+}
+
+// Messages returns the subscriber's queue. It is closed when the subscriber
+// is evicted or unsubscribed, or when the hub is closed.
+func (s *Subscriber) Messages() <-chan Message {
+	return s.send
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscriber]struct{}),
+		// Sequence numbers start at the current time so that they keep
+		// increasing across restarts. A client resuming from a previous run
+		// is then too far behind the backlog and gets a snapshot instead of
+		// an unrelated replay.
+		next: uint64(time.Now().UnixMicro()),
+	}
+}
+
+// Subscribe registers a new subscriber. When since is the sequence number of
+// a message still in the backlog, or of the latest message, the matching
+// messages published after it are returned and resumed is true.
+func (h *Hub) Subscribe(filter Filter, since uint64) (subscriber *Subscriber, missed []Message, resumed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subscriber = &Subscriber{
+		send:   make(chan Message, SubscriberBufferSize),
+		filter: filter,
+	}
+	if h.closed {
+		close(subscriber.send)
+		return subscriber, nil, false
+	}
+	h.subscribers[subscriber] = struct{}{}
+
+	oldest := h.next
+	if len(h.backlog) > 0 {
+		oldest = h.backlog[0].Seq
+	}
+	if since == 0 || since+1 < oldest || since >= h.next {
+		return subscriber, nil, false
+	}
+
+	for _, message := range h.backlog {
+		if message.Seq <= since {
+			continue
+		}
+		if message, ok := filter.narrow(message); ok {
+			missed = append(missed, message)
+		}
+	}
+	return subscriber, missed, true
+}
+
+// Unsubscribe removes a subscriber and closes its queue. It is safe to call
+// for a subscriber that was already evicted.
+func (h *Hub) Unsubscribe(subscriber *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(subscriber)
+}
+
+// Publish assigns the next sequence number to message and queues it for
+// every subscriber it matches, evicting those whose queue is full.
+func (h *Hub) Publish(message Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	message.Seq = h.next
+	h.next++
+	h.backlog = append(h.backlog, message)
+	if len(h.backlog) > BacklogSize {
+		h.backlog = h.backlog[len(h.backlog)-BacklogSize:]
+	}
+
+	for subscriber := range h.subscribers {
+		message, ok := subscriber.filter.narrow(message)
+		if !ok {
+			continue
+		}
+		select {
+		case subscriber.send <- message:
+		default:
+			h.remove(subscriber)
+		}
+	}
+}
+
+// Close removes every subscriber and closes their queues. Subscribers added
+// afterwards get a closed queue right away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for subscriber := range h.subscribers {
+		h.remove(subscriber)
+	}
+}
+
+// Closed reports whether the hub was closed, which tells subscribers whose
+// queue was closed apart from evicted ones.
+func (h *Hub) Closed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.closed
+}
+
+// Seq returns the sequence number of the latest message.
+func (h *Hub) Seq() uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.next - 1
+}
+
+// Len returns the number of subscribers.
+func (h *Hub) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers)
+}
+
+func (h *Hub) remove(subscriber *Subscriber) {
+	if _, ok := h.subscribers[subscriber]; !ok {
+		return
+	}
+	delete(h.subscribers, subscriber)
+	close(subscriber.send)
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub_Publish(t *testing.T) {
+	hub := NewHub()
+	subscriber, _, _ := hub.Subscribe(Filter{}, 0)
+
+	hub.Publish(Message{Type: MessageHistory})
+	message := <-subscriber.Messages()
+	assert.Equal(t, MessageHistory, message.Type)
+	assert.Equal(t, hub.Seq(), message.Seq)
+
+	hub.Unsubscribe(subscriber)
+	_, ok := <-subscriber.Messages()
+	assert.False(t, ok)
+	assert.Equal(t, 0, hub.Len())
+
+	// Unsubscribing twice is harmless
+	hub.Unsubscribe(subscriber)
+}
+
+func TestHub_EvictsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	slow, _, _ := hub.Subscribe(Filter{}, 0)
+	fast, _, _ := hub.Subscribe(Filter{}, 0)
+
+	for i := 0; i < SubscriberBufferSize+1; i++ {
+		hub.Publish(Message{Type: MessageResult})
+		<-fast.Messages()
+	}
+
+	assert.Equal(t, 1, hub.Len())
+	received := 0
+	for range slow.Messages() {
+		received++
+	}
+	assert.Equal(t, SubscriberBufferSize, received)
+}
+
+func TestHub_Close(t *testing.T) {
+	hub := NewHub()
+	subscriber, _, _ := hub.Subscribe(Filter{}, 0)
+	hub.Publish(Message{Type: MessageResult})
+	assert.False(t, hub.Closed())
+
+	hub.Close()
+	assert.True(t, hub.Closed())
+	assert.Equal(t, 0, hub.Len())
+
+	// Queued messages are still delivered before the queue ends
+	received := 0
+	for range subscriber.Messages() {
+		received++
+	}
+	assert.Equal(t, 1, received)
+
+	late, _, _ := hub.Subscribe(Filter{}, 0)
+	_, ok := <-late.Messages()
+	assert.False(t, ok)
+	assert.Equal(t, 0, hub.Len())
+
+	// Publishing after closing is harmless
+	hub.Publish(Message{Type: MessageResult})
+}
+
+func TestHub_Resume(t *testing.T) {
+	hub := NewHub()
+	hub.Publish(Message{Type: MessageResult, Monitor: "api"})
+	first := hub.Seq()
+	hub.Publish(Message{Type: MessageResult, Monitor: "web"})
+	hub.Publish(Message{Type: MessageHistory, Monitor: "api"})
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		since   uint64
+		missed  []string
+		resumed bool
+	}{
+		{name: "fresh", since: 0, resumed: false},
+		{name: "up to date", since: hub.Seq(), resumed: true},
+		{name: "missed all", since: first - 1, missed: []string{"api", "web", "api"}, resumed: true},
+		{name: "missed some", since: first, missed: []string{"web", "api"}, resumed: true},
+		{name: "filtered", filter: Filter{Monitors: []string{"api"}, Types: []string{MessageResult}}, since: first - 1, missed: []string{"api"}, resumed: true},
+		{name: "previous run", since: first - 100, resumed: false},
+		{name: "future", since: hub.Seq() + 1, resumed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscriber, missed, resumed := hub.Subscribe(tt.filter, tt.since)
+			defer hub.Unsubscribe(subscriber)
+
+			assert.Equal(t, tt.resumed, resumed)
+			monitors := make([]string, 0, len(missed))
+			for _, message := range missed {
+				monitors = append(monitors, message.Monitor)
+			}
+			assert.Equal(t, len(tt.missed), len(monitors))
+			if len(tt.missed) > 0 {
+				assert.Equal(t, tt.missed, monitors)
+			}
+		})
+	}
+}
+
+func TestFilter_Matches(t *testing.T) {
+	result := Message{Type: MessageResult, Monitor: "api", tags: []string{"backend"}}
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		message Message
+		matches bool
+	}{
+		{"empty filter", Filter{}, result, true},
+		{"monitor", Filter{Monitors: []string{"api"}}, result, true},
+		{"other monitor", Filter{Monitors: []string{"web"}}, result, false},
+		{"tag", Filter{Tags: []string{"backend"}}, result, true},
+		{"monitor or tag", Filter{Monitors: []string{"web"}, Tags: []string{"backend"}}, result, true},
+		{"type", Filter{Types: []string{MessageResult}}, result, true},
+		{"other type", Filter{Types: []string{MessageHistory}}, result, false},
+		{"message without monitor", Filter{Monitors: []string{"web"}}, Message{Type: MessageResult}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.matches, tt.filter.Matches(tt.message))
+		})
+	}
+}
+
+func TestStore_Subscribe(t *testing.T) {
+	s := NewStore()
+	s.UpdateResult(types.CheckResult{ID: "api", Tags: []string{"backend"}, Status: true})
+	s.UpdateResult(types.CheckResult{ID: "web", Status: false, Reason: "timeout"})
+
+	subscriber, messages := s.Subscribe(Filter{Tags: []string{"backend"}}, 0)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, MessageSnapshot, messages[0].Type)
+	snapshot := messages[0].Data.(Snapshot)
+	assert.Contains(t, snapshot.Results, "api")
+	assert.NotContains(t, snapshot.Results, "web")
+	assert.Empty(t, snapshot.Incidents)
+	assert.Len(t, snapshot.History, 1)
+	s.Hub().Unsubscribe(subscriber)
+
+	subscriber, messages = s.Subscribe(Filter{}, messages[0].Seq)
+	assert.Empty(t, messages)
+
+	s.UpdateResult(types.CheckResult{ID: "web", Status: true})
+	var received []string
+	for i := 0; i < 3; i++ {
+		received = append(received, (<-subscriber.Messages()).Type)
+	}
+	assert.Equal(t, []string{MessageResult, MessageIncidentResolved, MessageHistory}, received)
+}
+
+func TestStore_MarkPending(t *testing.T) {
+	s := NewStore()
+	subscriber, _ := s.Subscribe(Filter{Tags: []string{"web"}}, 0)
+	defer s.Hub().Unsubscribe(subscriber)
+
+	// A page that was never checked gets a placeholder
+	s.MarkPending(types.CheckResult{ID: "web", Tags: []string{"web"}}, types.CheckResult{ID: "api", Tags: []string{"api"}})
+	message := <-subscriber.Messages()
+	assert.Equal(t, MessagePending, message.Type)
+	// Batches only hold the monitors the subscriber is interested in
+	pending := message.Data.(map[string]interface{})
+	assert.Len(t, pending, 1)
+	assert.True(t, pending["web"].(types.CheckResult).Pending)
+	assert.False(t, s.GetResults()["web"].Checked())
+
+	// The previous result is kept while pending
+	checkedAt := time.Now()
+	s.UpdateResult(types.CheckResult{ID: "web", Tags: []string{"web"}, Status: true, LastChecked: checkedAt})
+	assert.False(t, s.GetResults()["web"].Pending)
+	s.MarkPending(types.CheckResult{ID: "web", Tags: []string{"web"}})
+	result := s.GetResults()["web"]
+	assert.True(t, result.Pending)
+	assert.True(t, result.Status)
+	assert.Equal(t, checkedAt, result.LastChecked)
+
+	// Pending pages don't show up in the history
+	assert.Len(t, s.GetHistory(), 1)
+}
+
+func TestStore_MarkPendingMany(t *testing.T) {
+	s := NewStore()
+	subscriber, _ := s.Subscribe(Filter{}, 0)
+	defer s.Hub().Unsubscribe(subscriber)
+	filtered, _ := s.Subscribe(Filter{Monitors: []string{"page-1"}}, 0)
+	defer s.Hub().Unsubscribe(filtered)
+	since := s.Hub().Seq()
+
+	// Queueing more pages than a subscriber's buffer holds doesn't evict it
+	results := make([]types.CheckResult, SubscriberBufferSize*10)
+	for i := range results {
+		results[i] = types.CheckResult{ID: fmt.Sprintf("page-%d", i)}
+	}
+	s.MarkPending(results...)
+	assert.Equal(t, 2, s.Hub().Len())
+
+	message := <-subscriber.Messages()
+	assert.Equal(t, MessagePending, message.Type)
+	assert.Len(t, message.Data, len(results))
+	message = <-filtered.Messages()
+	assert.Len(t, message.Data, 1)
+
+	// Resuming subscribers get the batch narrowed as well
+	resumed, missed := s.Subscribe(Filter{Monitors: []string{"page-2"}}, since)
+	defer s.Hub().Unsubscribe(resumed)
+	assert.Len(t, missed, 1)
+	assert.Contains(t, missed[0].Data, "page-2")
+	assert.Len(t, missed[0].Data, 1)
+
+	// Nothing is published without pages
+	s.MarkPending()
+	assert.Empty(t, subscriber.Messages())
+}
+
+func TestUpdateResult_DoesNotBlockOnSubscribers(t *testing.T) {
+	s := NewStore()
+	subscriber, _ := s.Subscribe(Filter{}, 0)
+
+	// Nobody reads from the subscriber, which must not block checks
+	for i := 0; i < SubscriberBufferSize*2; i++ {
+		s.UpdateResult(types.CheckResult{ID: "example", Status: true})
+	}
+
+	_, open := <-subscriber.Messages()
+	assert.True(t, open)
+	assert.Equal(t, 0, s.Hub().Len())
+}
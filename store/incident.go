@@ -0,0 +1,295 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/marshallku/statusy/types"
+)
+
+// maxIncidents bounds how many incidents are kept in memory. The oldest
+// resolved incidents are dropped first.
+const maxIncidents = 500
+
+// maxIncidentReasons bounds how many distinct failure reasons an incident
+// keeps. Reasons can include values from the response, e.g. a body hash, so
+// a page whose content keeps changing would otherwise grow its incident with
+// every check.
+const maxIncidentReasons = 20
+
+var (
+	ErrIncidentNotFound = errors.New("incident not found")
+	ErrIncidentResolved = errors.New("incident already resolved")
+)
+
+// trackIncident opens an incident when a page goes down, records the failure
+// reasons observed while it stays down and resolves it once the page
+// recovers. It returns the incident that changed, if any, and the type of the
+// message describing the change.
+// Callers must hold s.mu.
+func (s *Store) trackIncident(result types.CheckResult) (string, *types.Incident) {
+	incident, open := s.openIncidents[result.ID]
+
+	switch {
+	case !result.Status && !open:
+		incident = &types.Incident{
+			ID:        s.nextIncidentID,
+			MonitorID: result.ID,
+			Name:      result.Name,
+			URL:       result.URL,
+			StartedAt: result.LastChecked,
+		}
+		s.nextIncidentID++
+		incident.Timeline = append(incident.Timeline, types.IncidentEvent{
+			Type:      types.IncidentOpened,
+			Message:   "Page went down",
+			Timestamp: result.LastChecked,
+		})
+		addReason(incident, result)
+		s.incidents = append([]*types.Incident{incident}, s.incidents...)
+		s.openIncidents[result.ID] = incident
+		s.pruneIncidents()
+		return MessageIncidentOpened, incident
+	case !result.Status && open:
+		if addReason(incident, result) {
+			return MessageIncidentUpdated, incident
+		}
+	case result.Status && open:
+		resolvedAt := result.LastChecked
+		incident.ResolvedAt = &resolvedAt
+		incident.DurationSeconds = incident.Duration(resolvedAt).Seconds()
+		incident.Timeline = append(incident.Timeline, types.IncidentEvent{
+			Type:      types.IncidentResolved,
+			Message:   "Page recovered",
+			Timestamp: resolvedAt,
+		})
+		delete(s.openIncidents, result.ID)
+		return MessageIncidentResolved, incident
+	}
+
+	return "", nil
+}
+
+// addReason records a failure reason the first time it is observed during an
+// incident. Once maxIncidentReasons are recorded, failures with new reasons
+// are only counted. It reports whether the reasons or the timeline changed.
+func addReason(incident *types.Incident, result types.CheckResult) bool {
+	if result.Reason == "" {
+		return false
+	}
+	for _, reason := range incident.Reasons {
+		if reason == result.Reason {
+			return false
+		}
+	}
+	if len(incident.Reasons) >= maxIncidentReasons {
+		incident.OtherReasons++
+		return false
+	}
+	incident.Reasons = append(incident.Reasons, result.Reason)
+	incident.Timeline = append(incident.Timeline, types.IncidentEvent{
+		Type:      types.IncidentFailure,
+		Message:   result.Reason,
+		Timestamp: result.LastChecked,
+	})
+	return true
+}
+
+// Callers must hold s.mu.
+func (s *Store) pruneIncidents() {
+	for i := len(s.incidents) - 1; i >= 0 && len(s.incidents) > maxIncidents; i-- {
+		if !s.incidents[i].IsOpen() {
+			s.incidents = append(s.incidents[:i], s.incidents[i+1:]...)
+		}
+	}
+}
+
+// GetIncidents returns a snapshot of all incidents, newest first.
+func (s *Store) GetIncidents() []types.Incident {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	incidents := make([]types.Incident, 0, len(s.incidents))
+	for _, incident := range s.incidents {
+		incidents = append(incidents, snapshotIncident(incident, now))
+	}
+	return incidents
+}
+
+// GetIncident returns a snapshot of the incident with the given ID.
+func (s *Store) GetIncident(id int) (types.Incident, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	incident := s.findIncident(id)
+	if incident == nil {
+		return types.Incident{}, ErrIncidentNotFound
+	}
+	return snapshotIncident(incident, time.Now()), nil
+}
+
+// GetOpenIncident returns the unresolved incident of the given page, if any.
+func (s *Store) GetOpenIncident(monitorID string) (types.Incident, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	incident, ok := s.openIncidents[monitorID]
+	if !ok {
+		return types.Incident{}, false
+	}
+	return snapshotIncident(incident, time.Now()), true
+}
+
+// AcknowledgeIncident records that someone has taken ownership of an open
+// incident, which stops any further escalation.
+func (s *Store) AcknowledgeIncident(id int, by string) (types.Incident, error) {
+	s.mu.Lock()
+	incident := s.findIncident(id)
+	if incident == nil {
+		s.mu.Unlock()
+		return types.Incident{}, ErrIncidentNotFound
+	}
+	if !incident.IsOpen() {
+		snapshot := snapshotIncident(incident, time.Now())
+		s.mu.Unlock()
+		return snapshot, ErrIncidentResolved
+	}
+
+	now := time.Now()
+	incident.Acknowledged = true
+	incident.Acknowledgements = append(incident.Acknowledgements, types.Acknowledgement{
+		By: by,
+		At: now,
+	})
+	message := "Acknowledged"
+	if by != "" {
+		message = "Acknowledged by " + by
+	}
+	incident.Timeline = append(incident.Timeline, types.IncidentEvent{
+		Type:      types.IncidentAcknowledged,
+		Message:   message,
+		Timestamp: now,
+	})
+	snapshot := snapshotIncident(incident, now)
+	s.publish(MessageIncidentUpdated, incident.MonitorID, snapshot)
+	s.mu.Unlock()
+
+	return snapshot, nil
+}
+
+// AddIncidentUpdate attaches an operator-written note to an incident. Notes
+// can be added to resolved incidents as well, e.g. for a postmortem.
+func (s *Store) AddIncidentUpdate(id int, update types.IncidentUpdate) (types.Incident, error) {
+	s.mu.Lock()
+	incident := s.findIncident(id)
+	if incident == nil {
+		s.mu.Unlock()
+		return types.Incident{}, ErrIncidentNotFound
+	}
+
+	if update.CreatedAt.IsZero() {
+		update.CreatedAt = time.Now()
+	}
+	incident.Updates = append(incident.Updates, update)
+	incident.Timeline = append(incident.Timeline, types.IncidentEvent{
+		Type:      types.IncidentUpdated,
+		Message:   update.Message,
+		Timestamp: update.CreatedAt,
+	})
+	snapshot := snapshotIncident(incident, update.CreatedAt)
+	s.publish(MessageIncidentUpdated, incident.MonitorID, snapshot)
+	s.mu.Unlock()
+
+	return snapshot, nil
+}
+
+// RecordNotification records a notification sent to channel for the open
+// incident of the given page. Only the first notification per channel is
+// added to the timeline; subsequent ones are counted.
+func (s *Store) RecordNotification(monitorID string, channel string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident, ok := s.openIncidents[monitorID]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	for i := range incident.Notifications {
+		notification := &incident.Notifications[i]
+		if notification.Channel != channel {
+			continue
+		}
+		notification.Count++
+		notification.LastSentAt = now
+		if err != nil {
+			notification.Failed++
+		}
+		s.publish(MessageIncidentUpdated, monitorID, snapshotIncident(incident, now))
+		return
+	}
+
+	notification := types.IncidentNotification{
+		Channel:     channel,
+		Count:       1,
+		FirstSentAt: now,
+		LastSentAt:  now,
+	}
+	message := "Notified " + channel
+	if err != nil {
+		notification.Failed = 1
+		message = "Failed to notify " + channel + ": " + err.Error()
+	}
+	incident.Notifications = append(incident.Notifications, notification)
+	incident.Timeline = append(incident.Timeline, types.IncidentEvent{
+		Type:      types.IncidentNotified,
+		Message:   message,
+		Timestamp: now,
+	})
+	s.publish(MessageIncidentUpdated, monitorID, snapshotIncident(incident, now))
+}
+
+// SetEscalationLevel records how many escalation steps have been notified for
+// an incident.
+func (s *Store) SetEscalationLevel(id int, level int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	incident := s.findIncident(id)
+	if incident == nil || level == incident.EscalationLevel {
+		return
+	}
+	now := time.Now()
+	incident.EscalationLevel = level
+	incident.Timeline = append(incident.Timeline, types.IncidentEvent{
+		Type:      types.IncidentEscalated,
+		Message:   fmt.Sprintf("Escalated to level %d", level),
+		Timestamp: now,
+	})
+	s.publish(MessageIncidentUpdated, incident.MonitorID, snapshotIncident(incident, now))
+}
+
+// Callers must hold s.mu.
+func (s *Store) findIncident(id int) *types.Incident {
+	for _, incident := range s.incidents {
+		if incident.ID == id {
+			return incident
+		}
+	}
+	return nil
+}
+
+// snapshotIncident copies an incident so it can be used outside of s.mu.
+func snapshotIncident(incident *types.Incident, now time.Time) types.Incident {
+	snapshot := *incident
+	snapshot.DurationSeconds = incident.Duration(now).Seconds()
+	snapshot.Reasons = append([]string{}, incident.Reasons...)
+	snapshot.Notifications = append([]types.IncidentNotification{}, incident.Notifications...)
+	snapshot.Acknowledgements = append([]types.Acknowledgement{}, incident.Acknowledgements...)
+	snapshot.Updates = append([]types.IncidentUpdate{}, incident.Updates...)
+	snapshot.Timeline = append([]types.IncidentEvent{}, incident.Timeline...)
+	return snapshot
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncidentLifecycle(t *testing.T) {
+	s := NewStore()
+	id := "example"
+	start := time.Now()
+
+	s.UpdateResult(types.CheckResult{ID: id, Status: true, LastChecked: start})
+	assert.Empty(t, s.GetIncidents())
+
+	s.UpdateResult(types.CheckResult{ID: id, Status: false, Reason: "timeout", LastChecked: start.Add(time.Minute)})
+	s.UpdateResult(types.CheckResult{ID: id, Status: false, Reason: "timeout", LastChecked: start.Add(2 * time.Minute)})
+	s.UpdateResult(types.CheckResult{ID: id, Status: false, Reason: "bad status", LastChecked: start.Add(3 * time.Minute)})
+	s.RecordNotification(id, "webhook", nil)
+	s.RecordNotification(id, "webhook", errors.New("boom"))
+
+	incident, ok := s.GetOpenIncident(id)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"timeout", "bad status"}, incident.Reasons)
+	assert.Len(t, incident.Notifications, 1)
+	assert.Equal(t, 2, incident.Notifications[0].Count)
+	assert.Equal(t, 1, incident.Notifications[0].Failed)
+
+	_, err := s.AcknowledgeIncident(incident.ID, "jane")
+	assert.NoError(t, err)
+
+	s.UpdateResult(types.CheckResult{ID: id, Status: true, LastChecked: start.Add(5 * time.Minute)})
+
+	_, ok = s.GetOpenIncident(id)
+	assert.False(t, ok)
+
+	incident, err = s.GetIncident(incident.ID)
+	assert.NoError(t, err)
+	assert.False(t, incident.IsOpen())
+	assert.Equal(t, (4 * time.Minute).Seconds(), incident.DurationSeconds)
+	assert.Equal(t, "jane", incident.Acknowledgements[0].By)
+
+	events := make([]string, 0, len(incident.Timeline))
+	for _, event := range incident.Timeline {
+		events = append(events, event.Type)
+	}
+	assert.Equal(t, []string{"opened", "failure", "failure", "notified", "acknowledged", "resolved"}, events)
+
+	_, err = s.AcknowledgeIncident(incident.ID, "jane")
+	assert.ErrorIs(t, err, ErrIncidentResolved)
+	_, err = s.AcknowledgeIncident(incident.ID+1, "jane")
+	assert.ErrorIs(t, err, ErrIncidentNotFound)
+}
+
+func TestUpdateResult_SetsHistoryTimestamp(t *testing.T) {
+	s := NewStore()
+	checkedAt := time.Now()
+
+	s.UpdateResult(types.CheckResult{ID: "example", URL: "https://example.com", Status: true, LastChecked: checkedAt})
+
+	history := s.GetHistory()
+	assert.Len(t, history, 1)
+	assert.Equal(t, checkedAt, history[0].Timestamp)
+}
+
+func TestAddIncidentUpdate(t *testing.T) {
+	s := NewStore()
+	id := "example"
+
+	s.UpdateResult(types.CheckResult{ID: id, Status: false, LastChecked: time.Now()})
+	incident, _ := s.GetOpenIncident(id)
+
+	updated, err := s.AddIncidentUpdate(incident.ID, types.IncidentUpdate{Status: "Investigating", Message: "Looking into it"})
+	assert.NoError(t, err)
+	assert.Len(t, updated.Updates, 1)
+	assert.Equal(t, "Looking into it", updated.Updates[0].Message)
+	assert.False(t, updated.Updates[0].CreatedAt.IsZero())
+
+	_, err = s.AddIncidentUpdate(incident.ID+1, types.IncidentUpdate{Message: "nope"})
+	assert.ErrorIs(t, err, ErrIncidentNotFound)
+}
+
+func TestIncidentNotifications_Publish(t *testing.T) {
+	s := NewStore()
+	id := "example"
+
+	s.UpdateResult(types.CheckResult{ID: id, Status: false, LastChecked: time.Now()})
+	incident, _ := s.GetOpenIncident(id)
+	subscriber, _ := s.Subscribe(Filter{Types: []string{MessageIncidentUpdated}}, 0)
+	defer s.Hub().Unsubscribe(subscriber)
+
+	s.RecordNotification(id, "webhook", nil)
+	message := <-subscriber.Messages()
+	assert.Len(t, message.Data.(types.Incident).Notifications, 1)
+
+	s.RecordNotification(id, "webhook", errors.New("timeout"))
+	message = <-subscriber.Messages()
+	assert.Equal(t, 1, message.Data.(types.Incident).Notifications[0].Failed)
+
+	s.SetEscalationLevel(incident.ID, 1)
+	message = <-subscriber.Messages()
+	assert.Equal(t, id, message.Monitor)
+	assert.Equal(t, 1, message.Data.(types.Incident).EscalationLevel)
+}
+
+func TestIncidentReasons_Bounded(t *testing.T) {
+	s := NewStore()
+	id := "example"
+	start := time.Now()
+
+	// A reason that changes with every check, e.g. a body hash
+	for i := range maxIncidentReasons + 5 {
+		s.UpdateResult(types.CheckResult{ID: id, Status: false, Reason: fmt.Sprintf("body hash is %d", i), LastChecked: start.Add(time.Duration(i) * time.Second)})
+	}
+	// Repeated reasons aren't counted again
+	s.UpdateResult(types.CheckResult{ID: id, Status: false, Reason: "body hash is 0", LastChecked: start.Add(time.Hour)})
+
+	incident, _ := s.GetOpenIncident(id)
+	assert.Len(t, incident.Reasons, maxIncidentReasons)
+	assert.Equal(t, 5, incident.OtherReasons)
+	failures := 0
+	for _, event := range incident.Timeline {
+		if event.Type == types.IncidentFailure {
+			failures++
+		}
+	}
+	assert.Equal(t, maxIncidentReasons, failures)
+}
@@ -2,67 +2,159 @@ package store
 
 import (
 	"sync"
+	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/marshallku/statusy/types"
 )
 
 type Store struct {
-	mu        sync.RWMutex
-	results   map[string]types.CheckResult
-	history   []types.History
-	clients   map[*websocket.Conn]bool
-	broadcast chan Message
+	mu             sync.RWMutex
+	results        map[string]types.CheckResult
+	history        []types.History
+	uptime         map[string][]types.UptimeDay
+	incidents      []*types.Incident
+	openIncidents  map[string]*types.Incident
+	nextIncidentID int
+	hub            *Hub
 }
 
+// Message types published by the store.
+const (
+	// MessageSnapshot carries a Snapshot of the current state
+	MessageSnapshot = "snapshot"
+	// MessageResult carries the latest types.CheckResult of a monitor
+	MessageResult = "result"
+	// MessagePending carries the types.CheckResult, flagged as pending, of
+	// monitors queued for a check slot, keyed by monitor ID
+	MessagePending = "pending"
+	// MessageHistory carries a new types.History entry
+	MessageHistory = "history"
+	// MessageIncidentOpened, MessageIncidentUpdated and
+	// MessageIncidentResolved carry a types.Incident
+	MessageIncidentOpened   = "incident_opened"
+	MessageIncidentUpdated  = "incident_updated"
+	MessageIncidentResolved = "incident_resolved"
+)
+
 type Message struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data"`
+	Type string `json:"type"`
+	// Seq orders messages and lets clients resume after reconnecting
+	Seq uint64 `json:"seq,omitempty"`
+	// Monitor is the ID of the page the message is about, if any
+	Monitor string      `json:"monitor,omitempty"`
+	Data    interface{} `json:"data"`
+	// tags are the monitor's tags, used to filter messages
+	tags []string
+	// batch holds one entry per monitor for messages about several, which
+	// subscribers receive narrowed to the monitors they are interested in
+	batch []Message
+}
+
+// Snapshot is the state a subscriber starts from when it can't resume.
+type Snapshot struct {
+	Results   map[string]types.CheckResult `json:"results"`
+	Incidents []types.Incident             `json:"incidents"`
+	History   []types.History              `json:"history"`
 }
 
 func NewStore() *Store {
 	s := &Store{
-		results:   make(map[string]types.CheckResult),
-		history:   make([]types.History, 0),
-		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan Message),
+		results:        make(map[string]types.CheckResult),
+		history:        make([]types.History, 0),
+		uptime:         make(map[string][]types.UptimeDay),
+		incidents:      make([]*types.Incident, 0),
+		openIncidents:  make(map[string]*types.Incident),
+		nextIncidentID: 1,
+		hub:            NewHub(),
 	}
-	go s.handleBroadcast()
 	return s
 }
 
-func (s *Store) handleBroadcast() {
-	for message := range s.broadcast {
-		s.mu.RLock()
-		for client := range s.clients {
-			err := client.WriteJSON(message)
-			if err != nil {
-				client.Close()
-				delete(s.clients, client)
+// Hub returns the hub that store updates are published to.
+func (s *Store) Hub() *Hub {
+	return s.hub
+}
+
+// Subscribe registers a subscriber for the store's updates. The returned
+// messages bring the subscriber up to date: the ones it missed since the
+// given sequence number, or a snapshot when it can't resume.
+func (s *Store) Subscribe(filter Filter, since uint64) (*Subscriber, []Message) {
+	// Updates are published while s.mu is held, so no update can land
+	// between the snapshot and the subscription
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscriber, missed, resumed := s.hub.Subscribe(filter, since)
+	if resumed {
+		return subscriber, missed
+	}
+
+	return subscriber, []Message{{
+		Type: MessageSnapshot,
+		Seq:  s.hub.Seq(),
+		Data: s.snapshot(filter),
+	}}
+}
+
+// Callers must hold s.mu.
+func (s *Store) snapshot(filter Filter) Snapshot {
+	snapshot := Snapshot{
+		Results:   make(map[string]types.CheckResult),
+		Incidents: make([]types.Incident, 0),
+		History:   make([]types.History, 0),
+	}
+	matches := func(id string) bool {
+		return filter.matchesMonitor(id, s.results[id].Tags)
+	}
+
+	if filter.wants(MessageResult) {
+		for id, result := range s.results {
+			if matches(id) {
+				snapshot.Results[id] = result
 			}
 		}
-		s.mu.RUnlock()
 	}
+	if filter.wants(MessageIncidentOpened) || filter.wants(MessageIncidentUpdated) || filter.wants(MessageIncidentResolved) {
+		now := time.Now()
+		for _, incident := range s.incidents {
+			if matches(incident.MonitorID) {
+				snapshot.Incidents = append(snapshot.Incidents, snapshotIncident(incident, now))
+			}
+		}
+	}
+	if filter.wants(MessageHistory) {
+		for _, h := range s.history {
+			if matches(h.ID) {
+				snapshot.History = append(snapshot.History, h)
+			}
+		}
+	}
+	return snapshot
 }
 
-func (s *Store) AddClient(client *websocket.Conn) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.clients[client] = true
+// publish sends a message about a monitor to the hub.
+// Callers must hold s.mu so that messages are published in the order the
+// changes were made.
+func (s *Store) publish(messageType string, monitor string, data interface{}) {
+	s.hub.Publish(Message{
+		Type:    messageType,
+		Monitor: monitor,
+		Data:    data,
+		tags:    s.results[monitor].Tags,
+	})
 }
 
-func (s *Store) RemoveClient(client *websocket.Conn) {
+func (s *Store) UpdateResult(result types.CheckResult) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.clients, client)
-}
 
-func (s *Store) UpdateResult(result types.CheckResult) {
-	s.mu.Lock()
-	s.results[result.URL] = result
-	s.mu.Unlock()
+	s.results[result.ID] = result
+	s.recordUptime(result)
+	s.publish(MessageResult, result.ID, result)
 
-	s.broadcast <- Message{Type: "results", Data: s.GetResults()}
+	if messageType, incident := s.trackIncident(result); incident != nil {
+		s.publish(messageType, result.ID, snapshotIncident(incident, result.LastChecked))
+	}
 
 	// Add history
 	status := "UP"
@@ -70,31 +162,70 @@ func (s *Store) UpdateResult(result types.CheckResult) {
 		status = "DOWN"
 	}
 
-	s.AddHistory(types.History{
-		URL:    result.URL,
-		Status: status,
+	s.addHistory(types.History{
+		ID:        result.ID,
+		Name:      result.Name,
+		URL:       result.URL,
+		Status:    status,
+		Timestamp: result.LastChecked,
 	})
 }
 
+// MarkPending records that pages are queued for a free check slot. The
+// previous result of each is kept, flagged as pending, until its check
+// finishes; pages without one get their given result as a placeholder. The
+// pages are published in a single message, so that queueing many checks at
+// once doesn't flood subscribers.
+func (s *Store) MarkPending(results ...types.CheckResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := make([]Message, 0, len(results))
+	for _, result := range results {
+		if previous, ok := s.results[result.ID]; ok {
+			result = previous
+		}
+		result.Pending = true
+		s.results[result.ID] = result
+		batch = append(batch, Message{Monitor: result.ID, Data: result, tags: result.Tags})
+	}
+	s.hub.Publish(Message{Type: MessagePending, batch: batch})
+}
+
 func (s *Store) AddHistory(h types.History) {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addHistory(h)
+}
+
+// Callers must hold s.mu.
+func (s *Store) addHistory(h types.History) {
 	s.history = append([]types.History{h}, s.history...)
 	if len(s.history) > 10 {
 		s.history = s.history[:10]
 	}
-	s.mu.Unlock()
-
-	s.broadcast <- Message{Type: "history", Data: s.GetHistory()}
+	s.publish(MessageHistory, h.ID, h)
 }
 
+// GetResults returns a copy of the latest result of each page keyed by page
+// ID.
 func (s *Store) GetResults() map[string]types.CheckResult {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.results
+
+	results := make(map[string]types.CheckResult, len(s.results))
+	for id, result := range s.results {
+		results[id] = result
+	}
+	return results
 }
 
 func (s *Store) GetHistory() []types.History {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.history
+	return append([]types.History{}, s.history...)
 }
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"time"
+
+	"github.com/marshallku/statusy/types"
+)
+
+// UptimeDays is how many days of uptime are kept for each page.
+const UptimeDays = 90
+
+const dateLayout = "2006-01-02"
+
+// recordUptime adds a check result to the daily uptime of its page.
+// Callers must hold s.mu.
+func (s *Store) recordUptime(result types.CheckResult) {
+	date := result.LastChecked.UTC().Format(dateLayout)
+	days := s.uptime[result.ID]
+
+	if len(days) == 0 || days[len(days)-1].Date != date {
+		days = append(days, types.UptimeDay{Date: date})
+	}
+
+	today := &days[len(days)-1]
+	today.Checks++
+	if result.Status {
+		today.Up++
+	}
+	if result.StatusCode != 0 {
+		today.Responses++
+		today.TotalResponseTime += result.ResponseTime
+	}
+
+	if len(days) > UptimeDays {
+		days = days[len(days)-UptimeDays:]
+	}
+	s.uptime[result.ID] = days
+}
+
+// GetDailyUptime returns one entry per day for the given number of days up to
+// and including now, oldest first. Days without checks have zero counts.
+func (s *Store) GetDailyUptime(id string, days int, now time.Time) []types.UptimeDay {
+	s.mu.RLock()
+	recorded := make(map[string]types.UptimeDay, len(s.uptime[id]))
+	for _, day := range s.uptime[id] {
+		recorded[day.Date] = day
+	}
+	s.mu.RUnlock()
+
+	today := now.UTC()
+	uptime := make([]types.UptimeDay, days)
+	for i := range uptime {
+		date := today.AddDate(0, 0, i-days+1).Format(dateLayout)
+		day, ok := recorded[date]
+		if !ok {
+			day = types.UptimeDay{Date: date}
+		}
+		uptime[i] = day
+	}
+	return uptime
+}
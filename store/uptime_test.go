@@ -0,0 +1,33 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/marshallku/statusy/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDailyUptime(t *testing.T) {
+	s := NewStore()
+	id := "example"
+	now := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+
+	s.UpdateResult(types.CheckResult{ID: id, Status: true, LastChecked: now.AddDate(0, 0, -2)})
+	s.UpdateResult(types.CheckResult{ID: id, Status: true, LastChecked: now})
+	s.UpdateResult(types.CheckResult{ID: id, Status: false, LastChecked: now})
+
+	days := s.GetDailyUptime(id, 3, now)
+	assert.Equal(t, []types.UptimeDay{
+		{Date: "2024-11-18", Checks: 1, Up: 1},
+		{Date: "2024-11-19"},
+		{Date: "2024-11-20", Checks: 2, Up: 1},
+	}, days)
+
+	uptime, ok := days[2].Uptime()
+	assert.True(t, ok)
+	assert.Equal(t, 50.0, uptime)
+
+	_, ok = days[1].Uptime()
+	assert.False(t, ok)
+}
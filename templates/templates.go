@@ -2,6 +2,60 @@ package templates
 
 import "html/template"
 
+// escapeScript is shared by the pages rendering data with innerHTML. Names,
+// URLs, reasons and timeline messages come from the configuration and from
+// monitored servers, so they are escaped before they are rendered.
+const escapeScript = `
+        function escapeHTML(value) {
+            return String(value ?? '')
+                .replaceAll('&', '&amp;')
+                .replaceAll('<', '&lt;')
+                .replaceAll('>', '&gt;')
+                .replaceAll('"', '&quot;')
+                .replaceAll("'", '&#39;');
+        }
+`
+
+// subscribeScript is shared by the pages updated over the WebSocket.
+// subscribe reconnects with a backoff and resumes after the last message it
+// saw, so the server only sends a snapshot when updates were missed for too
+// long.
+const subscribeScript = `
+        function subscribe(params, onSnapshot, onMessage) {
+            let since = 0;
+            let delay = 1000;
+
+            function connect() {
+                const query = new URLSearchParams(params);
+                if (since) query.set('since', since);
+                // Relative to the page, so that a base path is respected
+                const url = new URL('ws', window.location.href);
+                url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
+                url.search = query;
+                const ws = new WebSocket(url);
+
+                ws.onopen = function() {
+                    delay = 1000;
+                };
+                ws.onmessage = function(event) {
+                    const message = JSON.parse(event.data);
+                    since = message.seq || since;
+                    if (message.type === 'snapshot') {
+                        onSnapshot(message.data);
+                    } else {
+                        onMessage(message);
+                    }
+                };
+                ws.onclose = function() {
+                    setTimeout(connect, delay);
+                    delay = Math.min(delay * 2, 30000);
+                };
+            }
+
+            connect();
+        }
+`
+
 var IndexTemplate = template.Must(template.New("index").Parse(`
 <!DOCTYPE html>
 <html>
@@ -17,49 +71,133 @@ var IndexTemplate = template.Must(template.New("index").Parse(`
         }
         .UP { background-color: #d4edda; }
         .DOWN { background-color: #f8d7da; }
+        .PENDING { background-color: #fff3cd; }
+        .incident-card {
+            border: 1px solid #f5c6cb;
+            padding: 10px;
+            margin: 10px 0;
+            border-radius: 4px;
+        }
         nav { margin-bottom: 20px; }
         nav a { margin-right: 10px; }
     </style>
 </head>
 <body>
     <nav>
-        <a href="/">Status</a>
-        <a href="/history">History</a>
+        <a href="./">Status</a>
+        <a href="history">History</a>
+        <a href="incidents">Incidents</a>
     </nav>
     <h1>Current Status</h1>
+    <div id="incident-container"></div>
+    <nav id="tag-filter"></nav>
     <div id="status-container"></div>
 
-    <script>
+    <script>` + escapeScript + subscribeScript + `
         const statusContainer = document.getElementById('status-container');
+        const incidentContainer = document.getElementById('incident-container');
+        const tagFilter = document.getElementById('tag-filter');
 
-        function updateStatus(results) {
-            statusContainer.innerHTML = Object.values(results)
-                .map(result => ` + "`" + `
-                    <div class="status-card ${result.Status}">
-                        <h3>${result.url}</h3>
-                        <p>Status: ${result.status ? 'UP' : 'DOWN'}</p>
-                        <p>Status Code: ${result.statusCode}</p>
-                        <p>Response Time: ${result.timeTaken}</p>
-                        <p>Last Checked: ${new Date(result.lastChecked).toLocaleString()}</p>
+        function updateIncidents(incidents) {
+            incidentContainer.innerHTML = incidents
+                .filter(incident => !incident.resolvedAt)
+                .map(incident => ` + "`" + `
+                    <div class="incident-card">
+                        <h3>Incident #${incident.id}: ${escapeHTML(incident.name)}</h3>
+                        <p>Down since: ${new Date(incident.startedAt).toLocaleString()}</p>
+                        ${incident.acknowledged
+                            ? ` + "`" + `<p>Acknowledged${incident.acknowledgements[0].by ? ' by ' + escapeHTML(incident.acknowledgements[0].by) : ''}</p>` + "`" + `
+                            : ` + "`" + `<button onclick="acknowledge(${incident.id})">Acknowledge</button>` + "`" + `}
                     </div>
                 ` + "`" + `).join('');
         }
 
-        // Connect to WebSocket
-        const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const ws = new WebSocket(protocol + '//' + window.location.host + '/ws');
+        function acknowledge(id) {
+            fetch('api/v1/incidents/' + id + '/ack', { method: 'POST' });
+        }
+
+        const selectedTag = new URLSearchParams(window.location.search).get('tag');
 
-        ws.onmessage = function(event) {
-            const data = JSON.parse(event.data);
-            if (data.type !== 'results') return;
-            updateStatus(data.data);
-        };
+        function renderResult(result) {
+            const checked = new Date(result.lastChecked).getFullYear() > 1;
+            const status = result.status ? 'UP' : 'DOWN';
+            return ` + "`" + `
+                <div class="status-card ${result.pending ? 'PENDING' : status}">
+                    <h3>${escapeHTML(result.name)}</h3>
+                    ${result.description ? ` + "`" + `<p>${escapeHTML(result.description)}</p>` + "`" + ` : ''}
+                    <p>URL: ${escapeHTML(result.url)}</p>
+                    <p>Status: ${result.pending ? 'PENDING' + (checked ? ` + "`" + ` (last ${status})` + "`" + ` : '') : status}</p>
+                    <p>Status Code: ${result.statusCode}</p>
+                    ${result.redirects ? ` + "`" + `<p>Redirects: ${result.redirects.map(hop => hop.statusCode + ' ' + escapeHTML(hop.url)).join(' → ')} → ${escapeHTML(result.finalUrl)}</p>` + "`" + ` : ''}
+                    <p>Response Time: ${escapeHTML(result.timeTaken)}</p>
+                    ${result.steps ? ` + "`" + `<ol>${result.steps.map(step => ` + "`" + `
+                        <li>${escapeHTML(step.name)}: ${step.status ? 'OK' : 'FAILED'} (${step.statusCode}, ${escapeHTML(step.timeTaken)})</li>
+                    ` + "`" + `).join('')}</ol>` + "`" + ` : ''}
+                    <p>Last Checked: ${checked ? new Date(result.lastChecked).toLocaleString() : 'Never'}</p>
+                </div>
+            ` + "`" + `;
+        }
+
+        function updateStatus(results) {
+            const groups = {};
+            const tags = new Set();
+
+            Object.values(results).forEach(result => {
+                const resultTags = result.tags && result.tags.length ? result.tags : [''];
+                resultTags.forEach(tag => {
+                    if (tag) tags.add(tag);
+                    if (selectedTag && tag !== selectedTag) return;
+                    (groups[tag] = groups[tag] || []).push(result);
+                });
+            });
+
+            tagFilter.innerHTML = ['<a href="?">All</a>']
+                .concat([...tags].sort().map(tag => ` + "`" + `<a href="?tag=${encodeURIComponent(tag)}">${escapeHTML(tag)}</a>` + "`" + `))
+                .join('');
+
+            statusContainer.innerHTML = Object.keys(groups)
+                .sort()
+                .map(tag => ` + "`" + `
+                    <h2>${escapeHTML(tag || 'Untagged')}</h2>
+                    ${groups[tag].sort((a, b) => a.name.localeCompare(b.name)).map(renderResult).join('')}
+                ` + "`" + `).join('');
+        }
 
-        ws.onclose = function() {
-            setTimeout(() => {
-                window.location.reload();
-            }, 1000);
-        };
+        let results = {};
+        let openIncidents = {};
+
+        function applyIncident(incident) {
+            if (incident.resolvedAt) {
+                delete openIncidents[incident.id];
+            } else {
+                openIncidents[incident.id] = incident;
+            }
+        }
+
+        function renderIncidents() {
+            updateIncidents(Object.values(openIncidents).sort((a, b) => b.id - a.id));
+        }
+
+        subscribe({ type: 'result,pending,incident_opened,incident_updated,incident_resolved' }, function(snapshot) {
+            results = snapshot.results;
+            openIncidents = {};
+            snapshot.incidents.forEach(applyIncident);
+            updateStatus(results);
+            renderIncidents();
+        }, function(message) {
+            if (message.type === 'result') {
+                results[message.monitor] = message.data;
+                updateStatus(results);
+                return;
+            }
+            if (message.type === 'pending') {
+                Object.assign(results, message.data);
+                updateStatus(results);
+                return;
+            }
+            applyIncident(message.data);
+            renderIncidents();
+        });
     </script>
 </body>
 </html>
@@ -84,42 +222,243 @@ var HistoryTemplate = template.Must(template.New("history").Parse(`
 </head>
 <body>
     <nav>
-        <a href="/">Status</a>
-        <a href="/history">History</a>
+        <a href="./">Status</a>
+        <a href="history">History</a>
+        <a href="incidents">Incidents</a>
     </nav>
     <h1>History (Last 10 Events)</h1>
     <div id="history-container"></div>
 
-    <script>
+    <script>` + escapeScript + subscribeScript + `
         const historyContainer = document.getElementById('history-container');
 
         function updateHistory(history) {
             historyContainer.innerHTML = history
                 .map(item => ` + "`" + `
                     <div class="history-item">
-                        <h3>${item.url}</h3>
-                        <p>Status: ${item.status}</p>
+                        <h3>${escapeHTML(item.name)}</h3>
+                        <p>URL: ${escapeHTML(item.url)}</p>
+                        <p>Status: ${escapeHTML(item.status)}</p>
                         <p>Time: ${new Date(item.timestamp).toLocaleString()}</p>
                     </div>
                 ` + "`" + `).join('');
         }
 
-        // Connect to WebSocket
-        const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-        const ws = new WebSocket(protocol + '//' + window.location.host + '/ws');
+        let history = [];
+
+        subscribe({ type: 'history' }, function(snapshot) {
+            history = snapshot.history;
+            updateHistory(history);
+        }, function(message) {
+            history = [message.data].concat(history).slice(0, 10);
+            updateHistory(history);
+        });
+    </script>
+</body>
+</html>
+`))
+
+var IncidentsTemplate = template.Must(template.New("incidents").Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+    <title>Statusy - Incidents</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 20px; }
+        .incident {
+            border: 1px solid #ddd;
+            padding: 10px;
+            margin: 10px 0;
+            border-radius: 4px;
+        }
+        .open { background-color: #f8d7da; }
+        .resolved { background-color: #d4edda; }
+        .timeline { font-size: 0.9em; color: #555; }
+        nav { margin-bottom: 20px; }
+        nav a { margin-right: 10px; }
+    </style>
+</head>
+<body>
+    <nav>
+        <a href="./">Status</a>
+        <a href="history">History</a>
+        <a href="incidents">Incidents</a>
+    </nav>
+    <h1>Incidents</h1>
+    <div id="incident-container"></div>
+
+    <script>` + escapeScript + subscribeScript + `
+        const incidentContainer = document.getElementById('incident-container');
+
+        function formatDuration(seconds) {
+            const hours = Math.floor(seconds / 3600);
+            const minutes = Math.floor((seconds % 3600) / 60);
+            const rest = Math.floor(seconds % 60);
+            return (hours ? hours + 'h ' : '') + (hours || minutes ? minutes + 'm ' : '') + rest + 's';
+        }
+
+        function updateIncidents(incidents) {
+            incidentContainer.innerHTML = incidents
+                .map(incident => ` + "`" + `
+                    <div class="incident ${incident.resolvedAt ? 'resolved' : 'open'}">
+                        <h3>#${incident.id} ${escapeHTML(incident.name)}</h3>
+                        <p>Started: ${new Date(incident.startedAt).toLocaleString()}</p>
+                        <p>${incident.resolvedAt
+                            ? 'Resolved: ' + new Date(incident.resolvedAt).toLocaleString()
+                            : 'Ongoing'} (${formatDuration(incident.durationSeconds)})</p>
+                        <p>Reasons: ${incident.reasons.map(escapeHTML).join(', ') || '-'}${incident.otherReasons ? ` + "`" + ` and ${incident.otherReasons} more failures` + "`" + ` : ''}</p>
+                        <form onsubmit="addUpdate(event, ${incident.id})">
+                            <input name="status" placeholder="Status (e.g. Investigating)">
+                            <input name="message" placeholder="Public update" required>
+                            <button type="submit">Post update</button>
+                        </form>
+                        <ul class="timeline">
+                            ${incident.timeline.map(event => ` + "`" + `
+                                <li>${new Date(event.timestamp).toLocaleString()} - ${escapeHTML(event.message)}</li>
+                            ` + "`" + `).join('')}
+                        </ul>
+                    </div>
+                ` + "`" + `).join('');
+        }
+
+        function addUpdate(event, id) {
+            event.preventDefault();
+            const form = event.target;
+            fetch('api/v1/incidents/' + id + '/updates', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({
+                    status: form.status.value,
+                    message: form.message.value,
+                }),
+            });
+        }
 
-        ws.onmessage = function(event) {
-            const data = JSON.parse(event.data);
-            if (data.type !== 'history') return;
-            updateHistory(data.data);
-        };
+        let incidents = {};
 
-        ws.onclose = function() {
-            setTimeout(() => {
-                window.location.reload();
-            }, 1000);
-        };
+        function renderIncidents() {
+            updateIncidents(Object.values(incidents).sort((a, b) => b.id - a.id));
+        }
+
+        subscribe({ type: 'incident_opened,incident_updated,incident_resolved' }, function(snapshot) {
+            incidents = {};
+            snapshot.incidents.forEach(incident => incidents[incident.id] = incident);
+            renderIncidents();
+        }, function(message) {
+            incidents[message.data.id] = message.data;
+            renderIncidents();
+        });
     </script>
 </body>
 </html>
 `))
+
+var StatusPageTemplate = template.Must(template.New("status").Parse(`
+<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Title}}</title>
+    <meta http-equiv="refresh" content="60">
+    <style>
+        body { font-family: Arial, sans-serif; margin: 20px auto; max-width: 900px; }
+        .banner {
+            padding: 15px;
+            border-radius: 4px;
+            font-weight: bold;
+        }
+        .operational { background-color: #d4edda; }
+        .outage { background-color: #f8d7da; }
+        .component {
+            border: 1px solid #ddd;
+            padding: 10px;
+            margin: 10px 0;
+            border-radius: 4px;
+        }
+        .component h2 { display: flex; justify-content: space-between; font-size: 1.2em; }
+        .monitor { margin: 10px 0; }
+        .monitor-header { display: flex; justify-content: space-between; }
+        .bars { display: flex; gap: 1px; margin-top: 5px; }
+        .bar { flex: 1; height: 30px; border-radius: 1px; }
+        .bar.up { background-color: #28a745; }
+        .bar.degraded { background-color: #ffc107; }
+        .bar.down { background-color: #dc3545; }
+        .bar.none { background-color: #e9ecef; }
+        .incident {
+            border-left: 4px solid #dc3545;
+            padding: 5px 10px;
+            margin: 10px 0;
+        }
+        .incident.resolved { border-left-color: #28a745; }
+        .update { margin: 5px 0; }
+        .muted { color: #777; font-size: 0.9em; }
+    </style>
+</head>
+<body>
+    <h1>{{.Title}}</h1>
+    {{if .AllUp}}
+    <div class="banner operational">All systems operational</div>
+    {{else}}
+    <div class="banner outage">Some systems are experiencing issues</div>
+    {{end}}
+
+    {{range .Components}}
+    <div class="component">
+        <h2><span>{{.Name}}</span><span>{{.Status}}</span></h2>
+        {{range .Monitors}}
+        <div class="monitor">
+            <div class="monitor-header">
+                <span>{{.Name}}</span>
+                <span>{{if not .Checked}}Pending{{else if .Up}}Up{{else}}Down{{end}} &middot; {{.Uptime}} uptime</span>
+            </div>
+            <div class="bars">
+                {{range .Days}}<div class="bar {{.Class}}" title="{{.Label}}"></div>{{end}}
+            </div>
+        </div>
+        {{end}}
+    </div>
+    {{end}}
+
+    <h2>Incidents</h2>
+    {{range .Incidents}}
+    <div class="incident {{if .ResolvedAt}}resolved{{end}}">
+        <strong>{{.Monitor}}</strong>
+        <p class="muted">
+            {{.StartedAt.Format "2006-01-02 15:04 MST"}}
+            {{if .ResolvedAt}} - {{.ResolvedAt.Format "2006-01-02 15:04 MST"}}{{else}} - ongoing{{end}}
+        </p>
+        {{range .Updates}}
+        <div class="update">
+            {{if .Status}}<strong>{{.Status}}</strong> - {{end}}{{.Message}}
+            <span class="muted">{{.CreatedAt.Format "2006-01-02 15:04 MST"}}</span>
+        </div>
+        {{end}}
+    </div>
+    {{else}}
+    <p class="muted">No incidents in the past 7 days.</p>
+    {{end}}
+
+    <p class="muted">Last updated {{.UpdatedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+</body>
+</html>
+`))
+
+var BadgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+    <title>{{.Label}}: {{.Message}}</title>
+    <linearGradient id="s" x2="0" y2="100%">
+        <stop offset="0" stop-color="#bbb" stop-opacity=".1"/>
+        <stop offset="1" stop-opacity=".1"/>
+    </linearGradient>
+    <clipPath id="r">
+        <rect width="{{.Width}}" height="20" rx="3" fill="#fff"/>
+    </clipPath>
+    <g clip-path="url(#r)">
+        <rect width="{{.LabelWidth}}" height="20" fill="#555"/>
+        <rect x="{{.LabelWidth}}" width="{{.Width}}" height="20" fill="{{.Color}}"/>
+        <rect width="{{.Width}}" height="20" fill="url(#s)"/>
+    </g>
+    <g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+        <text x="{{.LabelCenter}}" y="14">{{.Label}}</text>
+        <text x="{{.MessageCenter}}" y="14">{{.Message}}</text>
+    </g>
+</svg>
+`))
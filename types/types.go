@@ -3,15 +3,161 @@ package types
 import "time"
 
 type CheckResult struct {
-	URL         string    `json:"url"`
-	StatusCode  int       `json:"statusCode"`
-	TimeTaken   string    `json:"timeTaken"`
-	Status      bool      `json:"status"`
-	LastChecked time.Time `json:"lastChecked"`
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	Description   string       `json:"description,omitempty"`
+	Tags          []string     `json:"tags,omitempty"`
+	URL           string       `json:"url"`
+	StatusCode    int          `json:"statusCode"`
+	TimeTaken     string       `json:"timeTaken"`
+	ResponseTime  float64      `json:"responseTime"` // in milliseconds
+	Status        bool         `json:"status"`
+	Reason        string       `json:"reason,omitempty"`
+	Redirects     []Redirect   `json:"redirects,omitempty"`
+	FinalURL      string       `json:"finalUrl,omitempty"`
+	BodySize      int64        `json:"bodySize,omitempty"`      // bytes of the body read
+	BodyTruncated bool         `json:"bodyTruncated,omitempty"` // body exceeded the limit
+	Steps         []StepResult `json:"steps,omitempty"`
+	LastChecked   time.Time    `json:"lastChecked"`
+	Pending       bool         `json:"pending,omitempty"` // queued for a free check slot
+}
+
+// Checked reports whether the result comes from a finished check, rather than
+// standing in for a page that is pending its first check.
+func (r CheckResult) Checked() bool {
+	return !r.LastChecked.IsZero()
+}
+
+// StepResult is the outcome of one step of a multi-step page.
+type StepResult struct {
+	Name         string  `json:"name"`
+	URL          string  `json:"url"`
+	StatusCode   int     `json:"statusCode"`
+	TimeTaken    string  `json:"timeTaken"`
+	ResponseTime float64 `json:"responseTime"` // in milliseconds
+	Status       bool    `json:"status"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+// Redirect is one hop of a redirect chain.
+type Redirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	Location   string `json:"location"`
 }
 
 type History struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
 	URL       string    `json:"url"`
 	Status    string    `json:"status"`
 	Timestamp time.Time `json:"timestamp"`
 }
+
+const (
+	IncidentOpened       = "opened"
+	IncidentFailure      = "failure"
+	IncidentNotified     = "notified"
+	IncidentEscalated    = "escalated"
+	IncidentAcknowledged = "acknowledged"
+	IncidentResolved     = "resolved"
+	IncidentUpdated      = "updated"
+)
+
+type Incident struct {
+	ID              int        `json:"id"`
+	MonitorID       string     `json:"monitorId"`
+	Name            string     `json:"name"`
+	URL             string     `json:"url"`
+	StartedAt       time.Time  `json:"startedAt"`
+	ResolvedAt      *time.Time `json:"resolvedAt,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds"`
+	Reasons         []string   `json:"reasons"`
+	// OtherReasons counts failures whose reason wasn't recorded because the
+	// incident already holds as many distinct reasons as it keeps
+	OtherReasons     int                    `json:"otherReasons,omitempty"`
+	Notifications    []IncidentNotification `json:"notifications"`
+	Acknowledged     bool                   `json:"acknowledged"`
+	Acknowledgements []Acknowledgement      `json:"acknowledgements"`
+	EscalationLevel  int                    `json:"escalationLevel"`
+	Updates          []IncidentUpdate       `json:"updates"`
+	Timeline         []IncidentEvent        `json:"timeline"`
+}
+
+// IncidentUpdate is a note written by an operator, shown on the public status
+// page.
+type IncidentUpdate struct {
+	Status    string    `json:"status,omitempty"`
+	Message   string    `json:"message"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IncidentNotification aggregates the notifications sent to one channel
+// during an incident.
+type IncidentNotification struct {
+	Channel     string    `json:"channel"`
+	Count       int       `json:"count"`
+	Failed      int       `json:"failed"`
+	FirstSentAt time.Time `json:"firstSentAt"`
+	LastSentAt  time.Time `json:"lastSentAt"`
+}
+
+type Acknowledgement struct {
+	By string    `json:"by,omitempty"`
+	At time.Time `json:"at"`
+}
+
+type IncidentEvent struct {
+	Type      string    `json:"type"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func (i Incident) IsOpen() bool {
+	return i.ResolvedAt == nil
+}
+
+// Duration returns how long the incident lasted, or has lasted so far if it
+// is still open.
+func (i Incident) Duration(now time.Time) time.Duration {
+	if i.ResolvedAt != nil {
+		return i.ResolvedAt.Sub(i.StartedAt)
+	}
+	return now.Sub(i.StartedAt)
+}
+
+// UptimeDay counts the checks of a page during one UTC day.
+type UptimeDay struct {
+	Date              string  `json:"date"`
+	Checks            int     `json:"checks"`
+	Up                int     `json:"up"`
+	Responses         int     `json:"responses"`
+	TotalResponseTime float64 `json:"totalResponseTime"`
+}
+
+// Uptime returns the percentage of successful checks, or false when the page
+// was not checked that day.
+func (d UptimeDay) Uptime() (float64, bool) {
+	if d.Checks == 0 {
+		return 0, false
+	}
+	return float64(d.Up) / float64(d.Checks) * 100, true
+}
+
+// SummarizeUptime returns the uptime percentage and average response time in
+// milliseconds over the given days. ok is false when there were no checks.
+func SummarizeUptime(days []UptimeDay) (uptime float64, responseTime float64, ok bool) {
+	total := UptimeDay{}
+	for _, day := range days {
+		total.Checks += day.Checks
+		total.Up += day.Up
+		total.Responses += day.Responses
+		total.TotalResponseTime += day.TotalResponseTime
+	}
+
+	uptime, ok = total.Uptime()
+	if total.Responses > 0 {
+		responseTime = total.TotalResponseTime / float64(total.Responses)
+	}
+	return uptime, responseTime, ok
+}
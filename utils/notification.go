@@ -2,7 +2,9 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -11,6 +13,12 @@ import (
 	"github.com/marshallku/statusy/config"
 )
 
+var ErrWebhookNotSet = errors.New("webhook URL is not set")
+
+// notificationTimeout bounds how long a webhook may take so that a slow
+// webhook doesn't hold up the checks waiting for it.
+const notificationTimeout = 10 * time.Second
+
 type DiscordEmbed struct {
 	Type        string         `json:"type"`
 	Title       string         `json:"title"`
@@ -41,10 +49,15 @@ type NotificationParams struct {
 	Footer      string
 }
 
-func SendNotification(cfg *config.Config, params NotificationParams) {
-	if cfg.WebhookURL == "" {
-		fmt.Println("Webhook URL is not set")
-		return
+func SendNotification(ctx context.Context, cfg *config.Config, params NotificationParams) error {
+	return SendNotificationToWebhook(ctx, cfg.WebhookURL, params)
+}
+
+// SendNotificationToWebhook behaves like SendNotification but posts to the
+// given webhook instead of the globally configured one.
+func SendNotificationToWebhook(ctx context.Context, webhookURL string, params NotificationParams) error {
+	if webhookURL == "" {
+		return ErrWebhookNotSet
 	}
 
 	title := params.Title
@@ -52,7 +65,7 @@ func SendNotification(cfg *config.Config, params NotificationParams) {
 		title = "Health check failed"
 	}
 
-	sendDiscordNotification(cfg.WebhookURL, NotificationParams{
+	return sendDiscordNotification(ctx, webhookURL, NotificationParams{
 		Title:       title,
 		Description: params.Description,
 		Color:       params.Color,
@@ -61,7 +74,7 @@ func SendNotification(cfg *config.Config, params NotificationParams) {
 	})
 }
 
-func sendDiscordNotification(webhookURI string, params NotificationParams) {
+func sendDiscordNotification(ctx context.Context, webhookURI string, params NotificationParams) error {
 	colorInt, _ := strconv.Atoi(params.Color)
 
 	discordFields := make([]DiscordField, 0, len(params.Fields))
@@ -92,18 +105,27 @@ func sendDiscordNotification(webhookURI string, params NotificationParams) {
 
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		fmt.Printf("Error marshaling JSON: %v\n", err)
-		return
+		return fmt.Errorf("error marshaling JSON: %w", err)
 	}
 
-	resp, err := http.Post(webhookURI, "application/json", bytes.NewBuffer(jsonPayload))
+	ctx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURI, bytes.NewBuffer(jsonPayload))
 	if err != nil {
-		fmt.Printf("Error sending Discord notification: %v\n", err)
-		return
+		return fmt.Errorf("error creating Discord notification: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending Discord notification: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Discord API returned non-OK status: %d\n", resp.StatusCode)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("discord API returned non-OK status: %d", resp.StatusCode)
 	}
+
+	return nil
 }